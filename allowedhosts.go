@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+type allowedHostsConfig struct {
+	hosts      []string
+	statusCode int
+	exempt     func(*http.Request) bool
+}
+
+type AllowedHostsOption func(*allowedHostsConfig)
+
+// WithAllowedHosts appends one or more hosts to the allowlist. Hosts may start
+// with "*." to match any subdomain of the given domain (but not the domain
+// itself).
+func WithAllowedHosts(hosts ...string) AllowedHostsOption {
+	return func(config *allowedHostsConfig) {
+		for i := range hosts {
+			config.hosts = append(config.hosts, strings.ToLower(hosts[i]))
+		}
+	}
+}
+
+// WithAllowedHostsStatus sets the status code used to respond to requests for
+// hosts that aren't allowed. Defaults to 400 Bad Request.
+func WithAllowedHostsStatus(code int) AllowedHostsOption {
+	return func(config *allowedHostsConfig) {
+		config.statusCode = code
+	}
+}
+
+// WithAllowedHostsExempt sets a function to determine if a request should be
+// exempt from the allowlist, for example health checks that connect directly
+// to an IP address. The function should return true if the request should be
+// allowed regardless of its host.
+func WithAllowedHostsExempt(exempt func(*http.Request) bool) AllowedHostsOption {
+	return func(config *allowedHostsConfig) {
+		config.exempt = exempt
+	}
+}
+
+// AllowedHosts is a middleware that rejects requests whose Host header doesn't
+// match an allowlist configured with WithAllowedHosts. This defends against
+// Host header injection and cache poisoning attacks.
+//
+// Any port in the Host header is ignored when matching. Rejected requests are
+// responded to with a 400 response with no body, or the status configured with
+// WithAllowedHostsStatus. Chain this middleware with ErrorHandler to customise
+// this.
+func AllowedHosts(opts ...AllowedHostsOption) func(http.Handler) http.Handler {
+	config := &allowedHostsConfig{
+		statusCode: http.StatusBadRequest,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.exempt != nil && config.exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !hostAllowed(r.Host, config.hosts) {
+				w.WriteHeader(config.statusCode)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for i := range allowed {
+		if strings.HasPrefix(allowed[i], "*.") {
+			if strings.HasSuffix(host, allowed[i][1:]) {
+				return true
+			}
+		} else if host == allowed[i] {
+			return true
+		}
+	}
+	return false
+}
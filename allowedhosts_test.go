@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	handler := AllowedHosts(
+		WithAllowedHosts("example.com", "*.example.org"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		host           string
+		expectedStatus int
+	}{
+		{"exact match", "example.com", http.StatusOK},
+		{"exact match different case", "EXAMPLE.com", http.StatusOK},
+		{"exact match with port", "example.com:8080", http.StatusOK},
+		{"exact match trailing dot", "example.com.", http.StatusOK},
+		{"wildcard match", "www.example.org", http.StatusOK},
+		{"wildcard match nested", "a.b.example.org", http.StatusOK},
+		{"wildcard match with port", "www.example.org:443", http.StatusOK},
+		{"wildcard doesn't match bare domain", "example.org", http.StatusBadRequest},
+		{"wildcard doesn't match suffix", "evilexample.org", http.StatusBadRequest},
+		{"subdomain of exact", "www.example.com", http.StatusBadRequest},
+		{"disallowed host", "evil.com", http.StatusBadRequest},
+		{"empty host", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Host = tt.host
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestAllowedHosts_CustomStatus(t *testing.T) {
+	handler := AllowedHosts(
+		WithAllowedHosts("example.com"),
+		WithAllowedHostsStatus(http.StatusMisdirectedRequest),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Host = "evil.com"
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusMisdirectedRequest, rr.Code)
+}
+
+func TestAllowedHosts_Exempt(t *testing.T) {
+	handler := AllowedHosts(
+		WithAllowedHosts("example.com"),
+		WithAllowedHostsExempt(func(r *http.Request) bool {
+			host, _, _ := net.SplitHostPort(r.Host)
+			return r.URL.Path == "/health" && net.ParseIP(host) != nil
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		host           string
+		path           string
+		expectedStatus int
+	}{
+		{"health check by IP", "10.0.0.1:8080", "/health", http.StatusOK},
+		{"other path by IP", "10.0.0.1:8080", "/admin", http.StatusBadRequest},
+		{"health check by bad host", "evil.com:8080", "/health", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Host = tt.host
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
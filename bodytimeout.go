@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrBodyReadTimeout is returned when reading a request body wrapped by the
+// BodyReadTimeout middleware stalls for longer than the configured duration.
+var ErrBodyReadTimeout = errors.New("middleware: timed out reading request body")
+
+// BodyReadTimeout limits how long any single read of the request body may
+// stall. If no data arrives within d, the read returns ErrBodyReadTimeout,
+// protecting handlers from clients that deliberately trickle request bodies.
+//
+// Where the underlying connection supports it (Go 1.20 and later, with a
+// writer supported by http.ResponseController), a read deadline is set on the
+// connection before each read. Otherwise, reads are performed in the
+// background and abandoned if they don't complete in time.
+//
+// The middleware doesn't write a response itself; handlers should treat the
+// error like any other body read failure (for example by responding with
+// 408 Request Timeout).
+func BodyReadTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if setReadDeadline(w, time.Time{}) {
+				r.Body = &deadlineReader{
+					ReadCloser: r.Body,
+					writer:     w,
+					timeout:    d,
+				}
+				defer setReadDeadline(w, time.Time{})
+			} else {
+				r.Body = &timerReader{
+					ReadCloser: r.Body,
+					timeout:    d,
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deadlineReader sets a read deadline on the connection before each read.
+type deadlineReader struct {
+	io.ReadCloser
+	writer  http.ResponseWriter
+	timeout time.Duration
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	setReadDeadline(d.writer, time.Now().Add(d.timeout))
+	n, err := d.ReadCloser.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = ErrBodyReadTimeout
+	}
+	return n, err
+}
+
+type timerReadResult struct {
+	data []byte
+	err  error
+}
+
+// timerReader performs reads in a separate goroutine, giving up on them if
+// they take longer than the timeout. Once a read has timed out, all
+// subsequent reads fail.
+type timerReader struct {
+	io.ReadCloser
+	timeout time.Duration
+	buf     []byte
+	err     error
+}
+
+func (t *timerReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+
+	if cap(t.buf) < len(p) {
+		t.buf = make([]byte, len(p))
+	}
+	buf := t.buf[:len(p)]
+
+	result := make(chan timerReadResult, 1)
+	go func() {
+		n, err := t.ReadCloser.Read(buf)
+		result <- timerReadResult{data: buf[:n], err: err}
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-result:
+		return copy(p, res.data), res.err
+	case <-timer.C:
+		// The abandoned read may still write into the buffer, so stop reusing it.
+		t.buf = nil
+		t.err = ErrBodyReadTimeout
+		return 0, t.err
+	}
+}
@@ -0,0 +1,14 @@
+//go:build go1.20
+
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// setReadDeadline attempts to set the read deadline on the connection behind
+// w, returning false if it isn't supported.
+func setReadDeadline(w http.ResponseWriter, deadline time.Time) bool {
+	return http.NewResponseController(w).SetReadDeadline(deadline) == nil
+}
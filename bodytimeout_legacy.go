@@ -0,0 +1,14 @@
+//go:build !go1.20
+
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// setReadDeadline always returns false, as http.ResponseController is not
+// available before Go 1.20.
+func setReadDeadline(http.ResponseWriter, time.Time) bool {
+	return false
+}
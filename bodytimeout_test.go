@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingReader returns its data and then blocks until released.
+type stallingReader struct {
+	data    []byte
+	release chan struct{}
+}
+
+func (s *stallingReader) Read(p []byte) (int, error) {
+	if len(s.data) > 0 {
+		n := copy(p, s.data)
+		s.data = s.data[n:]
+		return n, nil
+	}
+	<-s.release
+	return 0, io.EOF
+}
+
+func TestBodyReadTimeout_FastBody(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := BodyReadTimeout(time.Second)(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello world"))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, readErr)
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestBodyReadTimeout_StalledBody(t *testing.T) {
+	reader := &stallingReader{data: []byte("hello"), release: make(chan struct{})}
+	defer close(reader.release)
+
+	var body []byte
+	var readErr error
+	handler := BodyReadTimeout(50 * time.Millisecond)(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", reader)
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrBodyReadTimeout)
+	assert.Equal(t, "hello", string(body))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestBodyReadTimeout_ReadsAfterTimeoutFail(t *testing.T) {
+	reader := &stallingReader{release: make(chan struct{})}
+	defer close(reader.release)
+
+	var errs []error
+	handler := BodyReadTimeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 10)
+		for i := 0; i < 2; i++ {
+			_, err := r.Body.Read(buf)
+			errs = append(errs, err)
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", reader)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[0], ErrBodyReadTimeout)
+	assert.ErrorIs(t, errs[1], ErrBodyReadTimeout)
+}
+
+func TestBodyReadTimeout_NoBody(t *testing.T) {
+	var gotBody io.ReadCloser
+	handler := BodyReadTimeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody = r.Body
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, http.NoBody, gotBody)
+}
+
+func TestBodyReadTimeout_Server(t *testing.T) {
+	readErr := make(chan error, 1)
+	handler := BodyReadTimeout(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	go func() {
+		req, err := http.NewRequest(http.MethodPost, server.URL, reader)
+		if err != nil {
+			return
+		}
+		if res, err := http.DefaultClient.Do(req); err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	_, err := writer.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	select {
+	case err := <-readErr:
+		assert.ErrorIs(t, err, ErrBodyReadTimeout)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not time out reading the body")
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+)
+
+type bufferResponseConfig struct {
+	maxSize int
+}
+
+type BufferResponseOption func(*bufferResponseConfig)
+
+// WithBufferMaxSize sets the maximum number of bytes of a response that will
+// be buffered. Responses larger than this are streamed to the client as they
+// are written, without a Content-Length header being added. Defaults to 1MiB.
+func WithBufferMaxSize(size int) BufferResponseOption {
+	return func(config *bufferResponseConfig) {
+		config.maxSize = size
+	}
+}
+
+// BufferResponse is a middleware that buffers responses in full before
+// sending them, so that a Content-Length header can be set. This avoids
+// responses being sent with chunked encoding, which some clients don't
+// handle well, and allows middleware earlier in the chain to see the
+// response's length.
+//
+// If the response exceeds the size set by WithBufferMaxSize, or the handler
+// explicitly flushes the response, the buffered data is sent and the rest of
+// the response is streamed as normal. Responses to HEAD requests are not
+// buffered.
+func BufferResponse(opts ...BufferResponseOption) func(http.Handler) http.Handler {
+	config := &bufferResponseConfig{
+		maxSize: 1024 * 1024,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &bufferResponseWrapper{
+				ResponseWriter: w,
+				conf:           config,
+				status:         http.StatusOK,
+			}
+			next.ServeHTTP(wrapped, r)
+			wrapped.finish()
+		})
+	}
+}
+
+type bufferResponseWrapper struct {
+	http.ResponseWriter
+	conf      *bufferResponseConfig
+	buffer    bytes.Buffer
+	status    int
+	headers   bool
+	streaming bool
+}
+
+func (b *bufferResponseWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		b.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if b.headers {
+		return
+	}
+
+	b.headers = true
+	b.status = code
+}
+
+func (b *bufferResponseWrapper) Write(p []byte) (int, error) {
+	if !b.headers {
+		b.WriteHeader(http.StatusOK)
+	}
+
+	if !b.streaming && b.buffer.Len()+len(p) > b.conf.maxSize {
+		if err := b.stream(); err != nil {
+			return 0, err
+		}
+	}
+
+	if b.streaming {
+		return b.ResponseWriter.Write(p)
+	}
+	return b.buffer.Write(p)
+}
+
+// stream sends the headers and any buffered data, and switches to writing
+// directly to the underlying writer.
+func (b *bufferResponseWrapper) stream() error {
+	b.streaming = true
+	b.ResponseWriter.WriteHeader(b.status)
+	if b.buffer.Len() == 0 {
+		return nil
+	}
+
+	_, err := b.ResponseWriter.Write(b.buffer.Bytes())
+	b.buffer = bytes.Buffer{}
+	return err
+}
+
+// finish sends the buffered response, if it hasn't been streamed.
+func (b *bufferResponseWrapper) finish() {
+	if b.streaming {
+		return
+	}
+
+	if b.status != http.StatusNoContent && b.status != http.StatusNotModified && b.ResponseWriter.Header().Get("Content-Length") == "" {
+		b.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(b.buffer.Len()))
+	}
+	b.ResponseWriter.WriteHeader(b.status)
+	if b.buffer.Len() > 0 {
+		b.ResponseWriter.Write(b.buffer.Bytes())
+	}
+}
+
+func (b *bufferResponseWrapper) Flush() {
+	if !b.streaming {
+		if !b.headers {
+			b.WriteHeader(http.StatusOK)
+		}
+		if b.stream() != nil {
+			return
+		}
+	}
+	if flusher, ok := b.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
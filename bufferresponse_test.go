@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var chunkedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	for i := 0; i < 10; i++ {
+		w.Write([]byte(strings.Repeat("a", 1000)))
+	}
+})
+
+func TestBufferResponse_SetsContentLength(t *testing.T) {
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello "))
+		w.Write([]byte("world"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "11", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "hello world", rr.Body.String())
+}
+
+func TestBufferResponse_EmptyResponse(t *testing.T) {
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("Content-Length"))
+}
+
+func TestBufferResponse_NoContent(t *testing.T) {
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+}
+
+func TestBufferResponse_KeepsExistingContentLength(t *testing.T) {
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		w.Write([]byte("hello"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, []string{"5"}, rr.Header().Values("Content-Length"))
+	assert.Equal(t, "hello", rr.Body.String())
+}
+
+func TestBufferResponse_MaxSize(t *testing.T) {
+	var bufferedDuringWrite []int
+	rr := httptest.NewRecorder()
+	handler := BufferResponse(WithBufferMaxSize(8))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		for _, s := range []string{"hello", "world", "again"} {
+			w.Write([]byte(s))
+			bufferedDuringWrite = append(bufferedDuringWrite, rr.Body.Len())
+		}
+	}))
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, []int{0, 10, 15}, bufferedDuringWrite)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+	assert.Equal(t, "helloworldagain", rr.Body.String())
+}
+
+func TestBufferResponse_Flush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	var flushed string
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		flushed = rr.Body.String()
+		w.Write([]byte(" world"))
+	}))
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, "hello", flushed)
+	assert.True(t, rr.Flushed)
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+	assert.Equal(t, "hello world", rr.Body.String())
+}
+
+func TestBufferResponse_Head(t *testing.T) {
+	handler := BufferResponse()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("HEAD", "/test", nil))
+
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+}
+
+func TestBufferResponse_Server(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		length   int64
+		encoding []string
+	}{
+		{"without middleware", chunkedHandler, -1, []string{"chunked"}},
+		{"with middleware", BufferResponse()(chunkedHandler), 10000, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			res, err := http.Get(server.URL)
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.length, res.ContentLength)
+			assert.Equal(t, tt.encoding, res.TransferEncoding)
+			assert.Len(t, body, 10000)
+		})
+	}
+}
+
+func TestBufferResponse_Informational(t *testing.T) {
+	handler := BufferResponse()(earlyHintsHandler)
+
+	informational, res, body := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int64(len("test content")), res.ContentLength)
+	assert.Equal(t, "test content", body)
+}
@@ -3,12 +3,27 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 type cacheControlConfig struct {
-	cacheTimes map[string]time.Duration
+	cacheTimes  map[string]time.Duration
+	ageFunc     func(*http.Request) time.Duration
+	noTransform map[string]bool
+	respectReq  bool
+	isAuthed    func(*http.Request) bool
+	authed      string
+	anon        string
+	vary        []string
+	expires     bool
+	noCookie    bool
+	privateAuth bool
+	staleIfErr  map[string]time.Duration
+	clock       func() time.Time
+	debug       *DebugRegistry
 }
 
 type CacheControlOption func(*cacheControlConfig)
@@ -25,6 +40,115 @@ func WithCacheTimes(cacheTimes map[string]time.Duration) CacheControlOption {
 	}
 }
 
+// WithAgeFunc sets a function that CacheControl will use to determine the age
+// of a response, for example when it is being served from an internal cache or
+// snapshot. If the function returns a positive duration, an Age header will be
+// set alongside the Cache-Control header.
+//
+// The Age header is only set when CacheControl is setting the Cache-Control
+// header itself.
+func WithAgeFunc(ageFunc func(*http.Request) time.Duration) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.ageFunc = ageFunc
+	}
+}
+
+// WithNoTransform adds the `no-transform` directive to responses with the given
+// content types, preventing proxies from altering them (e.g. by transcoding
+// images). As with WithCacheTimes, the `*` character can be used in place of a
+// subtype to match all subtypes.
+func WithNoTransform(types ...string) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		if config.noTransform == nil {
+			config.noTransform = make(map[string]bool)
+		}
+		for i := range types {
+			config.noTransform[types[i]] = true
+		}
+	}
+}
+
+// WithRespectRequestCacheControl sets whether CacheControl should skip adding
+// a max-age directive when the request has a Cache-Control header containing
+// `no-cache` or `no-store`.
+//
+// This doesn't cause the response to be regenerated (that's up to the
+// handler), but avoids telling intermediaries to cache it.
+func WithRespectRequestCacheControl(respect bool) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.respectReq = respect
+	}
+}
+
+// WithAuthAwareCaching sets the Cache-Control header to one of two raw values
+// depending on whether the request is authenticated, as determined by the
+// isAuthenticated predicate. For example, authenticated responses could use
+// `private, no-store` while anonymous ones use `public, max-age=3600`.
+//
+// The chosen value is used in place of the directives CacheControl would
+// otherwise generate from the content type. If it is empty, the normal
+// behaviour applies.
+func WithAuthAwareCaching(isAuthenticated func(*http.Request) bool, authed, anon string) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.isAuthed = isAuthenticated
+		config.authed = authed
+		config.anon = anon
+	}
+}
+
+// WithCacheVary adds the given header names to the Vary header whenever
+// CacheControl sets a Cache-Control header, so that caches don't serve
+// responses to requests that differ in those headers. Names already present in
+// the Vary header will not be duplicated.
+func WithCacheVary(names ...string) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.vary = append(config.vary, names...)
+	}
+}
+
+// WithExpiresHeader sets whether CacheControl should also set an Expires
+// header, for the benefit of old intermediaries that don't understand
+// Cache-Control. The Expires time is calculated from the max-age directive, so
+// is only set when CacheControl sets a max-age itself.
+func WithExpiresHeader(expires bool) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.expires = expires
+	}
+}
+
+// WithStaleIfError adds a `stale-if-error` directive to responses with the
+// given content types, allowing caches to keep serving them for the given
+// duration after they become stale if the origin server fails. As with
+// WithCacheTimes, the `*` character can be used in place of a subtype to match
+// all subtypes. The directive is only added alongside a max-age directive.
+func WithStaleIfError(durations map[string]time.Duration) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.staleIfErr = durations
+	}
+}
+
+// WithNoCacheOnSetCookie sets whether CacheControl should use the directives
+// `private, no-cache` for responses that set a cookie, instead of the
+// directives it would otherwise use. Such responses are likely to be specific
+// to a user, and shouldn't be stored by shared caches. This takes precedence
+// over WithAuthAwareCaching.
+func WithNoCacheOnSetCookie(noCache bool) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.noCookie = noCache
+	}
+}
+
+// WithPrivateWhenAuthorized sets whether CacheControl should use the
+// directives `private, no-store` for responses to requests with an
+// Authorization header, instead of the directives it would otherwise use.
+// Such responses are almost always specific to a user. This takes precedence
+// over WithNoCacheOnSetCookie and WithAuthAwareCaching.
+func WithPrivateWhenAuthorized(private bool) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.privateAuth = private
+	}
+}
+
 var defaultCacheTimes = map[string]time.Duration{
 	"application/*":        time.Hour * 24 * 365,
 	"application/xml":      time.Hour,
@@ -38,6 +162,14 @@ var defaultCacheTimes = map[string]time.Duration{
 	"video/*":              time.Hour * 24 * 365,
 }
 
+// WithCacheControlDebugRegistry registers CacheControl's settings with the
+// given registry, so they are included in the output of DebugConfig.
+func WithCacheControlDebugRegistry(registry *DebugRegistry) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.debug = registry
+	}
+}
+
 // CacheControl is a middleware that automatically sets a Cache-Control header
 // with a max-age based on the Content-Type header set by the next handler.
 //
@@ -50,15 +182,19 @@ var defaultCacheTimes = map[string]time.Duration{
 func CacheControl(opts ...CacheControlOption) func(http.Handler) http.Handler {
 	config := &cacheControlConfig{
 		cacheTimes: defaultCacheTimes,
+		clock:      time.Now,
 	}
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	config.debug.Register("CacheControl", config.debugSettings())
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			wrapped := &cacheControlWrapper{
 				ResponseWriter: w,
+				req:            r,
 				conf:           config,
 			}
 
@@ -67,13 +203,55 @@ func CacheControl(opts ...CacheControlOption) func(http.Handler) http.Handler {
 	}
 }
 
+// debugSettings describes the config for DebugConfig.
+func (c *cacheControlConfig) debugSettings() map[string]any {
+	durations := func(m map[string]time.Duration) map[string]string {
+		res := make(map[string]string, len(m))
+		for k, v := range m {
+			res[k] = v.String()
+		}
+		return res
+	}
+
+	noTransform := make([]string, 0, len(c.noTransform))
+	for t := range c.noTransform {
+		noTransform = append(noTransform, t)
+	}
+	sort.Strings(noTransform)
+
+	settings := map[string]any{
+		"cacheTimes":                 durations(c.cacheTimes),
+		"ageFunc":                    c.ageFunc != nil,
+		"noTransform":                noTransform,
+		"respectRequestCacheControl": c.respectReq,
+		"vary":                       c.vary,
+		"expiresHeader":              c.expires,
+		"noCacheOnSetCookie":         c.noCookie,
+		"privateWhenAuthorized":      c.privateAuth,
+		"staleIfError":               durations(c.staleIfErr),
+	}
+	if c.isAuthed != nil {
+		settings["authAwareCaching"] = map[string]string{
+			"authenticated": c.authed,
+			"anonymous":     c.anon,
+		}
+	}
+	return settings
+}
+
 type cacheControlWrapper struct {
 	http.ResponseWriter
+	req     *http.Request
 	conf    *cacheControlConfig
 	headers bool
 }
 
 func (c *cacheControlWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+
 	c.headers = true
 
 	if c.ResponseWriter.Header().Get("Cache-Control") != "" {
@@ -82,25 +260,104 @@ func (c *cacheControlWrapper) WriteHeader(code int) {
 		return
 	}
 
-	// See if we have a duration for the full type
-	contentType, _, _ := strings.Cut(c.Header().Get("Content-Type"), ";")
-	if t, ok := c.conf.cacheTimes[contentType]; ok {
-		c.ResponseWriter.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(t.Seconds())))
+	if c.conf.privateAuth && c.req.Header.Get("Authorization") != "" {
+		c.setCacheControl("private, no-store")
 		c.ResponseWriter.WriteHeader(code)
 		return
 	}
 
-	// If not try the main type ("audio", "image", etc)
-	mainType, _, _ := strings.Cut(contentType, "/")
-	if t, ok := c.conf.cacheTimes[fmt.Sprintf("%s/*", mainType)]; ok {
-		c.ResponseWriter.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(t.Seconds())))
+	if c.conf.noCookie && c.ResponseWriter.Header().Get("Set-Cookie") != "" {
+		c.setCacheControl("private, no-cache")
 		c.ResponseWriter.WriteHeader(code)
 		return
 	}
 
+	if c.conf.isAuthed != nil {
+		directives := c.conf.anon
+		if c.conf.isAuthed(c.req) {
+			directives = c.conf.authed
+		}
+		if directives != "" {
+			c.setCacheControl(directives)
+			c.ResponseWriter.WriteHeader(code)
+			return
+		}
+	}
+
+	contentType, _, _ := strings.Cut(c.Header().Get("Content-Type"), ";")
+
+	var directives []string
+	t, hasCacheTime := lookupContentType(c.conf.cacheTimes, contentType)
+	if hasCacheTime && c.conf.respectReq && requestForbidsCaching(c.req) {
+		hasCacheTime = false
+	}
+	if hasCacheTime {
+		directives = append(directives, fmt.Sprintf("max-age=%d", int(t.Seconds())))
+	}
+
+	if stale, ok := lookupContentType(c.conf.staleIfErr, contentType); ok && hasCacheTime {
+		directives = append(directives, fmt.Sprintf("stale-if-error=%d", int(stale.Seconds())))
+	}
+
+	if noTransform, _ := lookupContentType(c.conf.noTransform, contentType); noTransform {
+		directives = append(directives, "no-transform")
+	}
+
+	if len(directives) > 0 {
+		c.setCacheControl(strings.Join(directives, ", "))
+	}
+
+	if hasCacheTime && c.conf.expires {
+		c.ResponseWriter.Header().Set("Expires", c.conf.clock().Add(t).UTC().Format(http.TimeFormat))
+	}
+
+	if hasCacheTime && c.conf.ageFunc != nil {
+		if age := c.conf.ageFunc(c.req); age > 0 {
+			c.ResponseWriter.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+		}
+	}
+
 	c.ResponseWriter.WriteHeader(code)
 }
 
+// setCacheControl sets the Cache-Control header, and adds any configured names
+// to the Vary header.
+func (c *cacheControlWrapper) setCacheControl(value string) {
+	c.ResponseWriter.Header().Set("Cache-Control", value)
+	if len(c.conf.vary) > 0 {
+		addVary(c.ResponseWriter.Header(), c.conf.vary...)
+	}
+}
+
+// requestForbidsCaching determines whether the request has a Cache-Control
+// header with a `no-cache` or `no-store` directive.
+func requestForbidsCaching(r *http.Request) bool {
+	for _, v := range r.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive == "no-cache" || directive == "no-store" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupContentType finds the value for the given content type in a map, first
+// trying the full type and then falling back to a wildcard for the main type
+// (e.g. "image/*").
+func lookupContentType[T any](values map[string]T, contentType string) (T, bool) {
+	// See if we have a value for the full type
+	if v, ok := values[contentType]; ok {
+		return v, true
+	}
+
+	// If not try the main type ("audio", "image", etc)
+	mainType, _, _ := strings.Cut(contentType, "/")
+	v, ok := values[fmt.Sprintf("%s/*", mainType)]
+	return v, ok
+}
+
 func (c *cacheControlWrapper) Write(b []byte) (int, error) {
 	if !c.headers {
 		c.WriteHeader(http.StatusOK)
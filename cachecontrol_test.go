@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCacheControl_DefaultBehavior(t *testing.T) {
@@ -203,3 +204,416 @@ func TestCacheControl_MultipleWrites(t *testing.T) {
 	assert.Equal(t, "max-age=3600", rr.Header().Get("Cache-Control"))
 	assert.Equal(t, "first second", rr.Body.String())
 }
+
+func TestCacheControl_AgeFunc(t *testing.T) {
+	handler := CacheControl(WithAgeFunc(func(r *http.Request) time.Duration {
+		return time.Minute * 5
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "max-age=3600", rr.Header().Get("Cache-Control"))
+	assert.Equal(t, "300", rr.Header().Get("Age"))
+}
+
+func TestCacheControl_AgeFuncNotPositive(t *testing.T) {
+	handler := CacheControl(WithAgeFunc(func(r *http.Request) time.Duration {
+		return 0
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "max-age=3600", rr.Header().Get("Cache-Control"))
+	assert.Empty(t, rr.Header().Get("Age"))
+}
+
+func TestCacheControl_AgeFuncWithExistingCacheControl(t *testing.T) {
+	handler := CacheControl(WithAgeFunc(func(r *http.Request) time.Duration {
+		return time.Minute
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	assert.Empty(t, rr.Header().Get("Age"))
+}
+
+func TestCacheControl_NoTransform(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    string
+	}{
+		{"configured type", "image/png", "max-age=31536000, no-transform"},
+		{"wildcard type", "video/mp4", "max-age=31536000, no-transform"},
+		{"unconfigured type", "image/jpeg", "max-age=31536000"},
+		{"no cache time", "unknown/type", "no-transform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(WithNoTransform("image/png", "video/*", "unknown/type"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCacheControl_RespectRequestCacheControl(t *testing.T) {
+	tests := []struct {
+		name         string
+		respect      bool
+		requestCache string
+		expected     string
+	}{
+		{"no-store respected", true, "no-store", ""},
+		{"no-cache respected", true, "no-cache", ""},
+		{"multiple directives respected", true, "max-age=0, No-Store", ""},
+		{"other directives ignored", true, "max-age=0", "max-age=3600"},
+		{"no request header", true, "", "max-age=3600"},
+		{"not respected", false, "no-store", "max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(WithRespectRequestCacheControl(tt.respect))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.requestCache != "" {
+				req.Header.Set("Cache-Control", tt.requestCache)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCacheControl_AuthAwareCaching(t *testing.T) {
+	tests := []struct {
+		name          string
+		authed        string
+		anon          string
+		authorization string
+		handlerCache  string
+		expected      string
+	}{
+		{"authenticated", "private, no-store", "public, max-age=3600", "Bearer token", "", "private, no-store"},
+		{"anonymous", "private, no-store", "public, max-age=3600", "", "", "public, max-age=3600"},
+		{"empty directive falls back", "private, no-store", "", "", "", "max-age=3600"},
+		{"handler value preserved", "private, no-store", "public, max-age=3600", "Bearer token", "no-cache", "no-cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(WithAuthAwareCaching(func(r *http.Request) bool {
+				return r.Header.Get("Authorization") != ""
+			}, tt.authed, tt.anon))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				if tt.handlerCache != "" {
+					w.Header().Set("Cache-Control", tt.handlerCache)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCacheControl_CacheVary(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		existingVary string
+		expectedVary []string
+	}{
+		{"cached", "text/html", "", []string{"Accept-Encoding", "Cookie"}},
+		{"merged with existing", "text/html", "accept-encoding", []string{"accept-encoding", "Cookie"}},
+		{"not cached", "unknown/type", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(WithCacheVary("Accept-Encoding", "Cookie"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.existingVary != "" {
+					w.Header().Set("Vary", tt.existingVary)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedVary, rr.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestCacheControl_CacheVaryWithAuthAwareCaching(t *testing.T) {
+	handler := CacheControl(
+		WithCacheVary("Authorization"),
+		WithAuthAwareCaching(func(r *http.Request) bool { return false }, "private, no-store", "public, max-age=60"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
+	assert.Equal(t, "Authorization", rr.Header().Get("Vary"))
+}
+
+func TestCacheControl_Informational(t *testing.T) {
+	informational, res, body := serveWithInformational(t, CacheControl()(earlyHintsHandler), nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Empty(t, informational[http.StatusEarlyHints].Get("Cache-Control"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "max-age=3600", res.Header.Get("Cache-Control"))
+	assert.Equal(t, "test content", body)
+}
+
+func withCacheControlClock(t time.Time) CacheControlOption {
+	return func(config *cacheControlConfig) {
+		config.clock = func() time.Time { return t }
+	}
+}
+
+func TestCacheControl_ExpiresHeader(t *testing.T) {
+	now := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	tests := []struct {
+		name            string
+		contentType     string
+		handlerCache    string
+		expectedCache   string
+		expectedExpires string
+	}{
+		{"text", "text/html", "", "max-age=3600", "Tue, 10 Oct 2000 21:55:36 GMT"},
+		{"image", "image/png", "", "max-age=31536000", "Wed, 10 Oct 2001 20:55:36 GMT"},
+		{"no cache time", "unknown/type", "", "", ""},
+		{"handler cache control", "text/html", "no-cache", "no-cache", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(WithExpiresHeader(true), withCacheControlClock(now))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.handlerCache != "" {
+					w.Header().Set("Cache-Control", tt.handlerCache)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCache, rr.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.expectedExpires, rr.Header().Get("Expires"))
+		})
+	}
+}
+
+func TestCacheControl_ExpiresHeaderDisabledByDefault(t *testing.T) {
+	handler := CacheControl()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "max-age=3600", rr.Header().Get("Cache-Control"))
+	assert.Empty(t, rr.Header().Get("Expires"))
+}
+
+func TestCacheControl_NoCacheOnSetCookie(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []CacheControlOption
+		cookie        bool
+		handlerCache  string
+		expectedCache string
+	}{
+		{"cookie set", []CacheControlOption{WithNoCacheOnSetCookie(true)}, true, "", "private, no-cache"},
+		{"no cookie", []CacheControlOption{WithNoCacheOnSetCookie(true)}, false, "", "max-age=31536000"},
+		{"disabled by default", nil, true, "", "max-age=31536000"},
+		{"handler cache control", []CacheControlOption{WithNoCacheOnSetCookie(true)}, true, "public, max-age=60", "public, max-age=60"},
+		{
+			"overrides auth aware caching",
+			[]CacheControlOption{
+				WithNoCacheOnSetCookie(true),
+				WithAuthAwareCaching(func(*http.Request) bool { return false }, "private", "public, max-age=3600"),
+			},
+			true,
+			"",
+			"private, no-cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				if tt.cookie {
+					http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+				}
+				if tt.handlerCache != "" {
+					w.Header().Set("Cache-Control", tt.handlerCache)
+				}
+				w.Write([]byte("image"))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCache, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCacheControl_PrivateWhenAuthorized(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []CacheControlOption
+		authorization string
+		cookie        bool
+		handlerCache  string
+		expectedCache string
+	}{
+		{"authorized", []CacheControlOption{WithPrivateWhenAuthorized(true)}, "Bearer abc123", false, "", "private, no-store"},
+		{"unauthorized", []CacheControlOption{WithPrivateWhenAuthorized(true)}, "", false, "", "max-age=31536000"},
+		{"disabled by default", nil, "Bearer abc123", false, "", "max-age=31536000"},
+		{"handler cache control", []CacheControlOption{WithPrivateWhenAuthorized(true)}, "Bearer abc123", false, "public, max-age=60", "public, max-age=60"},
+		{
+			"overrides cookie and auth aware caching",
+			[]CacheControlOption{
+				WithPrivateWhenAuthorized(true),
+				WithNoCacheOnSetCookie(true),
+				WithAuthAwareCaching(func(*http.Request) bool { return true }, "private", "public, max-age=3600"),
+			},
+			"Bearer abc123",
+			true,
+			"",
+			"private, no-store",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				if tt.cookie {
+					http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+				}
+				if tt.handlerCache != "" {
+					w.Header().Set("Cache-Control", tt.handlerCache)
+				}
+				w.Write([]byte("image"))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCache, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestCacheControl_StaleIfError(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		requestCache  string
+		expectedCache string
+	}{
+		{"configured type", "text/html", "", "max-age=3600, stale-if-error=86400"},
+		{"wildcard type", "image/png", "", "max-age=31536000, stale-if-error=3600, no-transform"},
+		{"unconfigured type", "application/json", "", "max-age=3600"},
+		{"no max-age", "text/html", "no-cache", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CacheControl(
+				WithStaleIfError(map[string]time.Duration{
+					"text/html": 24 * time.Hour,
+					"image/*":   time.Hour,
+				}),
+				WithNoTransform("image/*"),
+				WithRespectRequestCacheControl(true),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.requestCache != "" {
+				req.Header.Set("Cache-Control", tt.requestCache)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCache, rr.Header().Get("Cache-Control"))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import "net/http"
 
 type chainConfig struct {
 	middleware []func(http.Handler) http.Handler
+	names      []string
 }
 
 type ChainOption func(*chainConfig)
@@ -12,6 +13,17 @@ type ChainOption func(*chainConfig)
 func WithMiddleware(middleware ...func(http.Handler) http.Handler) ChainOption {
 	return func(conf *chainConfig) {
 		conf.middleware = append(conf.middleware, middleware...)
+		conf.names = append(conf.names, make([]string, len(middleware))...)
+	}
+}
+
+// WithNamedMiddleware appends a single middleware to the chain, tagged with the
+// given name. The names of middleware in a chain can be retrieved with
+// ChainNames, which can help when debugging.
+func WithNamedMiddleware(name string, middleware func(http.Handler) http.Handler) ChainOption {
+	return func(conf *chainConfig) {
+		conf.middleware = append(conf.middleware, middleware)
+		conf.names = append(conf.names, name)
 	}
 }
 
@@ -35,8 +47,36 @@ func Chain(opts ...ChainOption) func(http.Handler) http.Handler {
 			next = m(next)
 		}
 
-		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			next.ServeHTTP(w, req)
-		})
+		var names []string
+		for _, name := range conf.names {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+
+		return &chainHandler{
+			next:  next,
+			names: names,
+		}
+	}
+}
+
+// ChainNames returns the names of the middleware applied by a Chain, in the
+// order they were configured (i.e., innermost first). Only middleware added
+// with WithNamedMiddleware are included. If the handler was not created by
+// Chain, returns nil.
+func ChainNames(h http.Handler) []string {
+	if c, ok := h.(*chainHandler); ok {
+		return append([]string(nil), c.names...)
 	}
+	return nil
+}
+
+type chainHandler struct {
+	next  http.Handler
+	names []string
+}
+
+func (c *chainHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.next.ServeHTTP(w, req)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -165,3 +166,68 @@ func TestChain_MiddlewareNotAppliedMultipleTimes(t *testing.T) {
 	// Verify middleware was only called once per request, not accumulated
 	assert.Equal(t, 2, callCount)
 }
+
+func TestChain_NamedMiddleware(t *testing.T) {
+	var order []string
+	named := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := Chain(
+		WithNamedMiddleware("cache", named("cache")),
+		WithMiddleware(named("unnamed")),
+		WithNamedMiddleware("log", named("log")),
+		WithNamedMiddleware("recover", named("recover")),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, []string{"cache", "log", "recover"}, ChainNames(handler))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, []string{"recover", "log", "unnamed", "cache"}, order)
+}
+
+func TestChainNames_NotAChain(t *testing.T) {
+	assert.Nil(t, ChainNames(http.NotFoundHandler()))
+}
+
+func TestChain_PartialContent(t *testing.T) {
+	content := strings.Repeat("<p>hello</p>", 20)
+	handler := Chain(
+		WithMiddleware(
+			TransformResponse(func(_ string, body []byte) []byte { return []byte("transformed") }),
+			BufferResponse(),
+			Compress(WithCompressionDisableForRange(false)),
+			CacheControl(),
+			Headers(WithHeader("X-Test", "value")),
+			TextLog(WithTextLogSink(func(string) {})),
+			Recover(),
+		),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Range", "bytes 0-99/240")
+		w.Header().Set("Content-Length", "100")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(content[:100]))
+	}))
+
+	req := httptest.NewRequest("GET", "/page", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-99")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "bytes 0-99/240", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "100", rr.Header().Get("Content-Length"))
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "value", rr.Header().Get("X-Test"))
+	assert.Equal(t, content[:100], rr.Body.String())
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type negotiatedCharsetKey struct{}
+
+// NegotiateCharset is a middleware that selects the best charset to respond
+// with from the supported charsets (e.g. "utf-8"), based on the request's
+// Accept-Charset header. The selected charset, as given in the list of
+// supported charsets, can be retrieved by handlers using NegotiatedCharset.
+//
+// Charsets are matched case-insensitively, and the "*" wildcard matches any
+// charset not otherwise mentioned. Supported charsets earlier in the list are
+// preferred when the client has no preference between them. Requests with no
+// Accept-Charset header are treated as accepting any charset, and are given
+// the first supported charset.
+//
+// If none of the supported charsets are acceptable, the request is responded
+// to with a 406 Not Acceptable response. Chain this middleware with
+// ErrorHandler to customise this.
+func NegotiateCharset(supported ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), "Accept-Charset")
+
+			accept := r.Header.Values("Accept-Charset")
+			if len(accept) == 0 {
+				accept = []string{"*"}
+			}
+
+			selected := selectCharset(parseMediaRanges(accept), supported)
+			if selected == "" {
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), negotiatedCharsetKey{}, selected)))
+		})
+	}
+}
+
+// NegotiatedCharset returns the charset selected by the NegotiateCharset
+// middleware, or an empty string if the middleware was not used.
+func NegotiatedCharset(r *http.Request) string {
+	if c, ok := r.Context().Value(negotiatedCharsetKey{}).(string); ok {
+		return c
+	}
+	return ""
+}
+
+func selectCharset(ranges map[string]float64, supported []string) string {
+	best := ""
+	bestValue := 0.0
+	for _, charset := range supported {
+		value, ok := ranges[strings.ToLower(charset)]
+		if !ok {
+			value = ranges["*"]
+		}
+
+		if value > bestValue {
+			best = charset
+			bestValue = value
+		}
+	}
+	return best
+}
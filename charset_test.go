@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateCharset(t *testing.T) {
+	tests := []struct {
+		name           string
+		supported      []string
+		acceptCharset  []string
+		expectedStatus int
+		expected       string
+	}{
+		{"exact match", []string{"utf-8", "iso-8859-1"}, []string{"iso-8859-1"}, http.StatusOK, "iso-8859-1"},
+		{"case insensitive", []string{"UTF-8"}, []string{"utf-8"}, http.StatusOK, "UTF-8"},
+		{"q-values", []string{"utf-8", "iso-8859-1"}, []string{"utf-8;q=0.5, iso-8859-1;q=0.8"}, http.StatusOK, "iso-8859-1"},
+		{"earlier preferred on tie", []string{"utf-8", "iso-8859-1"}, []string{"iso-8859-1, utf-8"}, http.StatusOK, "utf-8"},
+		{"multiple headers", []string{"utf-8", "iso-8859-1"}, []string{"utf-16", "iso-8859-1"}, http.StatusOK, "iso-8859-1"},
+		{"wildcard", []string{"utf-8"}, []string{"iso-8859-1, *;q=0.1"}, http.StatusOK, "utf-8"},
+		{"wildcard does not override explicit", []string{"utf-8", "iso-8859-1"}, []string{"utf-8;q=0, *"}, http.StatusOK, "iso-8859-1"},
+		{"no header", []string{"utf-8", "iso-8859-1"}, nil, http.StatusOK, "utf-8"},
+		{"no match", []string{"utf-8"}, []string{"iso-8859-1"}, http.StatusNotAcceptable, ""},
+		{"only charset refused", []string{"utf-8"}, []string{"utf-8;q=0"}, http.StatusNotAcceptable, ""},
+		{"only charset refused with wildcard", []string{"utf-8"}, []string{"*, utf-8;q=0"}, http.StatusNotAcceptable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var negotiated string
+			called := false
+			handler := NegotiateCharset(tt.supported...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				negotiated = NegotiatedCharset(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			for _, v := range tt.acceptCharset {
+				req.Header.Add("Accept-Charset", v)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, called)
+			assert.Equal(t, tt.expected, negotiated)
+			assert.Equal(t, "Accept-Charset", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestNegotiatedCharset_NoMiddleware(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	assert.Equal(t, "", NegotiatedCharset(req))
+}
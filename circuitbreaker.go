@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type circuitBreakerConfig struct {
+	failureThreshold int
+	cooldown         time.Duration
+	key              func(*http.Request) string
+	clock            func() time.Time
+}
+
+type CircuitBreakerOption func(*circuitBreakerConfig)
+
+// WithFailureThreshold sets the number of consecutive failed (5xx) responses
+// that will cause the circuit breaker to open. Defaults to 5.
+func WithFailureThreshold(threshold int) CircuitBreakerOption {
+	return func(config *circuitBreakerConfig) {
+		config.failureThreshold = threshold
+	}
+}
+
+// WithCooldown sets how long the circuit breaker stays open before allowing a
+// trial request through. Defaults to 30 seconds.
+func WithCooldown(cooldown time.Duration) CircuitBreakerOption {
+	return func(config *circuitBreakerConfig) {
+		config.cooldown = cooldown
+	}
+}
+
+// WithBreakerKey sets a function that determines which circuit a request
+// belongs to, allowing independent circuits for different routes or backends.
+// By default, all requests share a single circuit.
+func WithBreakerKey(key func(*http.Request) string) CircuitBreakerOption {
+	return func(config *circuitBreakerConfig) {
+		config.key = key
+	}
+}
+
+// CircuitBreaker is a middleware that fails fast when the next handler is
+// consistently failing.
+//
+// After the configured number of consecutive 5xx responses, the circuit opens
+// and requests are responded to with a 503 response without calling the next
+// handler. Once the cooldown has elapsed, the circuit becomes half-open and a
+// single trial request is allowed through: if it succeeds the circuit closes,
+// otherwise it opens again for another cooldown period.
+func CircuitBreaker(opts ...CircuitBreakerOption) func(http.Handler) http.Handler {
+	config := &circuitBreakerConfig{
+		failureThreshold: 5,
+		cooldown:         time.Second * 30,
+		key:              func(*http.Request) string { return "" },
+		clock:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var mutex sync.Mutex
+	circuits := make(map[string]*circuit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := config.key(r)
+
+			mutex.Lock()
+			c, ok := circuits[key]
+			if !ok {
+				c = &circuit{}
+				circuits[key] = c
+			}
+			generation, allowed := c.allow(config)
+			mutex.Unlock()
+
+			if !allowed {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			wrapped := &statusRecorder{
+				ResponseWriter: w,
+			}
+
+			completed := false
+			defer func() {
+				mutex.Lock()
+				// A panicking handler counts as a failure
+				c.record(config, generation, !completed || wrapped.status >= 500)
+				mutex.Unlock()
+			}()
+
+			next.ServeHTTP(wrapped, r)
+			completed = true
+		})
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuit tracks the state of a single circuit. The generation is incremented
+// every time the state changes, so that the outcomes of requests admitted
+// under an earlier state can be ignored.
+type circuit struct {
+	state      circuitState
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	trial      bool
+}
+
+// allow determines whether a request should be passed to the next handler,
+// moving from open to half-open if the cooldown has elapsed. It returns the
+// generation the request was admitted under, to be passed to record.
+func (c *circuit) allow(config *circuitBreakerConfig) (uint64, bool) {
+	switch c.state {
+	case circuitOpen:
+		if config.clock().Sub(c.openedAt) < config.cooldown {
+			return 0, false
+		}
+		c.transition(circuitHalfOpen)
+		c.trial = true
+		return c.generation, true
+
+	case circuitHalfOpen:
+		if c.trial {
+			// Only one trial request is allowed at a time
+			return 0, false
+		}
+		c.trial = true
+		return c.generation, true
+
+	default:
+		return c.generation, true
+	}
+}
+
+// record updates the circuit's state after a request has completed. Requests
+// admitted under an earlier generation are ignored: they started before the
+// circuit last changed state, so say nothing about whether it should change
+// again (and in particular aren't the half-open trial request).
+func (c *circuit) record(config *circuitBreakerConfig, generation uint64, failed bool) {
+	if generation != c.generation {
+		return
+	}
+
+	if c.state == circuitHalfOpen {
+		c.trial = false
+		if failed {
+			c.open(config)
+		} else {
+			c.transition(circuitClosed)
+		}
+		return
+	}
+
+	if !failed {
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.failures >= config.failureThreshold {
+		c.open(config)
+	}
+}
+
+// open moves the circuit to the open state, starting the cooldown.
+func (c *circuit) open(config *circuitBreakerConfig) {
+	c.transition(circuitOpen)
+	c.openedAt = config.clock()
+}
+
+// transition moves the circuit to the given state, starting a new generation.
+func (c *circuit) transition(state circuitState) {
+	c.state = state
+	c.generation++
+	c.failures = 0
+	c.trial = false
+}
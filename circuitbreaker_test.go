@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withBreakerClock(clock func() time.Time) CircuitBreakerOption {
+	return func(config *circuitBreakerConfig) {
+		config.clock = clock
+	}
+}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := http.StatusInternalServerError
+	calls := 0
+
+	handler := CircuitBreaker(
+		WithFailureThreshold(3),
+		WithCooldown(time.Minute),
+		withBreakerClock(func() time.Time { return now }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+
+	serve := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		return rr.Code
+	}
+
+	// Closed: failures are passed through until the threshold is reached
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusInternalServerError, serve())
+	}
+	assert.Equal(t, 3, calls)
+
+	// Open: requests are short-circuited
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, 3, calls)
+
+	// Half-open: a failed trial reopens the circuit
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusInternalServerError, serve())
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, 4, calls)
+
+	// Half-open: a successful trial closes the circuit
+	now = now.Add(time.Minute)
+	status = http.StatusOK
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, 6, calls)
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	statuses := []int{500, 500, 200, 500, 500, 200}
+	calls := 0
+
+	handler := CircuitBreaker(WithFailureThreshold(3))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls])
+		calls++
+	}))
+
+	for i := range statuses {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		assert.Equal(t, statuses[i], rr.Code)
+	}
+}
+
+func TestCircuitBreaker_ClientErrorsIgnored(t *testing.T) {
+	handler := CircuitBreaker(WithFailureThreshold(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestCircuitBreaker_PanicCountsAsFailure(t *testing.T) {
+	handler := CircuitBreaker(WithFailureThreshold(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestCircuitBreaker_Key(t *testing.T) {
+	handler := CircuitBreaker(
+		WithFailureThreshold(1),
+		WithBreakerKey(func(r *http.Request) string { return r.URL.Path }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadGateway, serve("/broken"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/broken"))
+	assert.Equal(t, http.StatusOK, serve("/working"))
+}
+
+func TestCircuitBreaker_LateCompletionsIgnored(t *testing.T) {
+	var clockMutex sync.Mutex
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	started := make(chan struct{})
+	releases := map[string]chan struct{}{
+		"/slow-a": make(chan struct{}),
+		"/slow-b": make(chan struct{}),
+		"/trial":  make(chan struct{}),
+	}
+
+	handler := CircuitBreaker(
+		WithFailureThreshold(2),
+		WithCooldown(time.Minute),
+		withBreakerClock(func() time.Time {
+			clockMutex.Lock()
+			defer clockMutex.Unlock()
+			return now
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if release, ok := releases[r.URL.Path]; ok {
+			started <- struct{}{}
+			<-release
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	serve := func(path string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code
+	}
+	serveAsync := func(path string) chan int {
+		result := make(chan int, 1)
+		go func() { result <- serve(path) }()
+		<-started
+		return result
+	}
+
+	// Two slow requests are admitted while the circuit is closed, and it
+	// opens while they're running
+	slowA := serveAsync("/slow-a")
+	slowB := serveAsync("/slow-b")
+	assert.Equal(t, http.StatusInternalServerError, serve("/fail"))
+	assert.Equal(t, http.StatusInternalServerError, serve("/fail"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/fail"))
+
+	// A late success doesn't close the open circuit
+	close(releases["/slow-a"])
+	assert.Equal(t, http.StatusOK, <-slowA)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/fail"))
+
+	// A late success while the trial is running doesn't close the circuit or
+	// allow a second trial
+	clockMutex.Lock()
+	now = now.Add(time.Minute)
+	clockMutex.Unlock()
+	trial := serveAsync("/trial")
+	close(releases["/slow-b"])
+	assert.Equal(t, http.StatusOK, <-slowB)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/fail"))
+
+	// Once the real trial succeeds, the circuit closes
+	close(releases["/trial"])
+	assert.Equal(t, http.StatusOK, <-trial)
+	assert.Equal(t, http.StatusInternalServerError, serve("/fail"))
+}
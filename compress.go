@@ -1,15 +1,38 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type compressConfig struct {
-	gzipLevel        int
-	compressionCheck func(*http.Request) bool
+	gzipLevel           int
+	levelsByType        map[string]int
+	compressionCheck    func(*http.Request) bool
+	noCompressionHeader string
+	flateDictionary     []byte
+	bufferSize          int
+	vary                []string
+	minLength           int
+	excludePaths        []string
+	minRatio            float64
+	skipRange           bool
+	flushInterval       time.Duration
+	flushBytes          int
+	clock               func() time.Time
+	maxConcurrent       int
+	slots               chan struct{}
+	writers             sync.Pool
+	buffers             sync.Pool
+	debug               *DebugRegistry
 }
 
 type CompressOption func(*compressConfig)
@@ -21,6 +44,17 @@ func WithGzipLevel(level int) CompressOption {
 	}
 }
 
+// WithGzipLevelByType sets the compression level to use for responses with
+// particular content types, allowing e.g. text to be compressed more heavily
+// than types that don't compress well. The `*` character can be used in place
+// of a subtype (e.g. `text/*`) to match all subtypes. Responses with other
+// content types use the level set by WithGzipLevel.
+func WithGzipLevelByType(levels map[string]int) CompressOption {
+	return func(config *compressConfig) {
+		config.levelsByType = levels
+	}
+}
+
 // WithCompressionCheck sets a function to determine if a request should be compressed.
 // The function should return true if compression should be applied, false otherwise.
 // Compression is still subject to the client sending the appropriate Accent-Encoding header.
@@ -30,50 +64,261 @@ func WithCompressionCheck(check func(*http.Request) bool) CompressOption {
 	}
 }
 
+// WithCompressExcludePaths sets path prefixes that should never be compressed,
+// such as endpoints that serve already-compressed files. Requests with a path
+// starting with any of the prefixes are passed to the next handler unmodified.
+func WithCompressExcludePaths(prefixes ...string) CompressOption {
+	return func(config *compressConfig) {
+		config.excludePaths = append(config.excludePaths, prefixes...)
+	}
+}
+
+// WithNoCompressionHeader sets the name of a response header that handlers can
+// set to prevent their response from being compressed. The header is removed
+// before the response is sent. Defaults to "X-No-Compression".
+func WithNoCompressionHeader(name string) CompressOption {
+	return func(config *compressConfig) {
+		config.noCompressionHeader = name
+	}
+}
+
+// WithFlateDictionary sets a preset dictionary to use when compressing
+// responses, which can dramatically improve compression of many small,
+// similar responses.
+//
+// When a dictionary is set, responses to clients that accept the "deflate"
+// encoding will be compressed as raw deflate data using the dictionary, in
+// preference to gzip. The client must be able to decode raw deflate data with
+// the same dictionary, so this is only suitable for internal services where
+// both sides are under your control.
+func WithFlateDictionary(dictionary []byte) CompressOption {
+	return func(config *compressConfig) {
+		config.flateDictionary = dictionary
+	}
+}
+
+// WithCompressBufferSize sets the size of the buffers used when writing
+// compressed responses. Buffers are pooled and reused between requests.
+// Defaults to 32KiB.
+func WithCompressBufferSize(size int) CompressOption {
+	return func(config *compressConfig) {
+		config.bufferSize = size
+	}
+}
+
+// WithVary adds extra header names to the Vary header of compressed responses,
+// alongside Accept-Encoding. Names that are already present in the Vary header
+// will not be duplicated.
+func WithVary(names ...string) CompressOption {
+	return func(config *compressConfig) {
+		config.vary = append(config.vary, names...)
+	}
+}
+
+// WithMinLength sets the minimum size of response that will be compressed.
+// If the handler sets a Content-Length header before writing the response and
+// it is smaller than this, the response is sent uncompressed. Responses with
+// no Content-Length header are always compressed. By default, there is no
+// minimum.
+func WithMinLength(length int) CompressOption {
+	return func(config *compressConfig) {
+		config.minLength = length
+	}
+}
+
+// WithGzipFlushInterval makes Compress flush compressed data to the client
+// if at least the given duration has passed since it was last flushed, so
+// long-lived streaming responses are received steadily without the handler
+// having to call Flush. The interval is checked each time the handler writes
+// data; nothing is flushed in the background. By default, data is only
+// flushed when the buffer fills or the handler calls Flush.
+func WithGzipFlushInterval(interval time.Duration) CompressOption {
+	return func(config *compressConfig) {
+		config.flushInterval = interval
+	}
+}
+
+// WithGzipFlushBytes makes Compress flush compressed data to the client each
+// time the handler has written at least the given number of (uncompressed)
+// bytes since the last flush. By default, data is only flushed when the
+// buffer fills or the handler calls Flush.
+func WithGzipFlushBytes(bytes int) CompressOption {
+	return func(config *compressConfig) {
+		config.flushBytes = bytes
+	}
+}
+
+// WithMaxConcurrentCompressions limits the number of responses that will be
+// compressed at the same time, to avoid saturating the CPU. When the limit is
+// reached, responses are served uncompressed rather than waiting. By default,
+// there is no limit.
+func WithMaxConcurrentCompressions(n int) CompressOption {
+	return func(config *compressConfig) {
+		config.maxConcurrent = n
+	}
+}
+
+// WithCompressionMinRatio makes Compress only use compression when it helps.
+// Responses are buffered in full and compressed, and the compressed version is
+// only sent if the ratio of the original size to the compressed size is at
+// least the given value (e.g. 1.25 requires the compressed response to be at
+// most 80% of the original size). Otherwise, the original is sent uncompressed.
+//
+// This requires holding both the whole response and its compressed form in
+// memory, and prevents any of the response being sent (including via
+// http.Flusher) until the handler has finished, so is not suitable for large
+// or streaming responses. By default, responses are streamed and compression is
+// always used.
+func WithCompressionMinRatio(ratio float64) CompressOption {
+	return func(config *compressConfig) {
+		config.minRatio = ratio
+	}
+}
+
+// WithCompressionDisableForRange sets whether responses to requests with a
+// Range header should be served uncompressed. Compressing a partial response
+// would make the offsets in its Content-Range header refer to the compressed
+// data rather than the resource, breaking resumed downloads and seeking.
+// Defaults to true.
+func WithCompressionDisableForRange(disable bool) CompressOption {
+	return func(config *compressConfig) {
+		config.skipRange = disable
+	}
+}
+
+// WithCompressDebugRegistry registers Compress's settings with the given
+// registry, so they are included in the output of DebugConfig.
+func WithCompressDebugRegistry(registry *DebugRegistry) CompressOption {
+	return func(config *compressConfig) {
+		config.debug = registry
+	}
+}
+
 // Compress is a middleware that automatically compresses the response body
 // if the client will accept it. It supports gzip encoding.
 //
 // If an invalid gzip level is set with WithGzipLevel, requests will be silently
 // served with no compression.
+//
+// Handlers can opt out of compression for a response by setting the header
+// configured with WithNoCompressionHeader to any value, or by setting the
+// Content-Encoding header to "identity" (which is removed from the response).
+// Responses to requests with a Range header are not compressed, unless
+// WithCompressionDisableForRange is set to false. Partial responses (those
+// with a 206 status or a Content-Range header) are never compressed.
 func Compress(opts ...CompressOption) func(http.Handler) http.Handler {
 	config := &compressConfig{
-		gzipLevel: gzip.DefaultCompression,
+		gzipLevel:           gzip.DefaultCompression,
+		noCompressionHeader: "X-No-Compression",
+		bufferSize:          32 * 1024,
+		skipRange:           true,
+		clock:               time.Now,
 	}
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	if config.bufferSize <= 0 {
+		config.bufferSize = 32 * 1024
+	}
+
+	if config.maxConcurrent > 0 {
+		config.slots = make(chan struct{}, config.maxConcurrent)
+	}
+
+	config.debug.Register("Compress", map[string]any{
+		"gzipLevel":            config.gzipLevel,
+		"gzipLevelByType":      config.levelsByType,
+		"compressionCheck":     config.compressionCheck != nil,
+		"noCompressionHeader":  config.noCompressionHeader,
+		"flateDictionaryBytes": len(config.flateDictionary),
+		"bufferSize":           config.bufferSize,
+		"vary":                 config.vary,
+		"minLength":            config.minLength,
+		"excludePaths":         config.excludePaths,
+		"minRatio":             config.minRatio,
+		"disableForRange":      config.skipRange,
+		"flushInterval":        config.flushInterval.String(),
+		"flushBytes":           config.flushBytes,
+		"maxConcurrent":        config.maxConcurrent,
+	})
+
+	config.writers.New = func() any {
+		return bufio.NewWriterSize(nil, config.bufferSize)
+	}
+	config.buffers.New = func() any {
+		b := make([]byte, config.bufferSize)
+		return &b
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Check if compression should be applied
-			if config.compressionCheck != nil && !config.compressionCheck(r) {
+			if config.excluded(r.URL.Path) || (config.compressionCheck != nil && !config.compressionCheck(r)) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			encs := parseEncodings(r.Header.Values("Accept-Encoding"))
-			if encs["gzip"] > 0 || encs["*"] > 0 {
-				writer, err := gzip.NewWriterLevel(w, config.gzipLevel)
-				if err != nil {
-					// Bad gzip level, just serve unencoded response
-					next.ServeHTTP(w, r)
-					return
-				}
+			wrapped := &gzipWrapper{
+				ResponseWriter: w,
+				conf:           config,
+			}
 
-				defer writer.Close()
-				next.ServeHTTP(&gzipWrapper{
-					ResponseWriter: w,
-					w:              writer,
-				}, r)
-			} else {
-				next.ServeHTTP(&gzipWrapper{
-					ResponseWriter: w,
-				}, r)
+			if !config.skipRange || r.Header.Get("Range") == "" {
+				encs := parseEncodings(r.Header.Values("Accept-Encoding"))
+				if config.flateDictionary != nil && encs["deflate"] > 0 {
+					wrapped.encoding = "deflate"
+				} else if encs["gzip"] > 0 || encs["*"] > 0 {
+					wrapped.encoding = "gzip"
+				}
 			}
+
+			defer wrapped.Close()
+			next.ServeHTTP(wrapped, r)
 		})
 	}
 }
 
+// excluded determines whether the path matches one of the excluded prefixes.
+func (c *compressConfig) excluded(path string) bool {
+	for _, prefix := range c.excludePaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newWriter creates a writer that compresses data using the given encoding.
+func (c *compressConfig) newWriter(encoding string, level int, w io.Writer) (io.WriteCloser, error) {
+	if encoding == "deflate" {
+		return flate.NewWriterDict(w, level, c.flateDictionary)
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// addVary adds the given header names to the Vary header, unless they (or a
+// wildcard) are already present.
+func addVary(header http.Header, names ...string) {
+	existing := make(map[string]bool)
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			existing[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	if existing["*"] {
+		return
+	}
+
+	for _, name := range names {
+		if !existing[strings.ToLower(name)] {
+			header.Add("Vary", name)
+			existing[strings.ToLower(name)] = true
+		}
+	}
+}
+
 func parseEncodings(encoding []string) map[string]float64 {
 	codings := make(map[string]float64)
 	for i := range encoding {
@@ -92,33 +337,233 @@ func parseEncodings(encoding []string) map[string]float64 {
 	return codings
 }
 
+// gzipWrapper compresses the response using the negotiated encoding (which
+// is not necessarily gzip). The compressing writer is created when the headers
+// are written, and its output is buffered using a pooled bufio.Writer.
 type gzipWrapper struct {
 	http.ResponseWriter
-	w       *gzip.Writer
-	headers bool
+	w        io.WriteCloser
+	buffer   *bufio.Writer
+	encoding string
+	conf     *compressConfig
+	headers  bool
+	status   int
+	buffered *bytes.Buffer
+
+	unflushed int
+	lastFlush time.Time
+	slot      bool
 }
 
 func (g *gzipWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		g.ResponseWriter.WriteHeader(code)
+		return
+	}
+
 	g.headers = true
-	g.ResponseWriter.Header().Add("Vary", "Accept-Encoding")
-	if g.w != nil {
-		g.ResponseWriter.Header().Set("Content-Encoding", "gzip")
-		g.ResponseWriter.Header().Del("Content-Length")
+	addVary(g.ResponseWriter.Header(), append([]string{"Accept-Encoding"}, g.conf.vary...)...)
+	if g.conf.noCompressionHeader != "" && g.ResponseWriter.Header().Get(g.conf.noCompressionHeader) != "" {
+		g.ResponseWriter.Header().Del(g.conf.noCompressionHeader)
+		g.encoding = ""
+	}
+	if strings.EqualFold(strings.TrimSpace(g.ResponseWriter.Header().Get("Content-Encoding")), "identity") {
+		g.ResponseWriter.Header().Del("Content-Encoding")
+		g.encoding = ""
+	}
+	if code == http.StatusPartialContent || g.ResponseWriter.Header().Get("Content-Range") != "" {
+		// Compressing would invalidate the byte offsets in Content-Range
+		g.encoding = ""
+	}
+	if g.conf.minLength > 0 {
+		length, err := strconv.Atoi(g.ResponseWriter.Header().Get("Content-Length"))
+		if err == nil && length < g.conf.minLength {
+			g.encoding = ""
+		}
+	}
+
+	if g.encoding != "" && !g.acquireSlot() {
+		g.encoding = ""
+	}
+
+	if g.encoding != "" && g.conf.minRatio > 0 {
+		// Hold the response until we know whether compression helps
+		g.status = code
+		g.buffered = &bytes.Buffer{}
+		return
+	}
+
+	if g.encoding != "" {
+		buffer := g.conf.writers.Get().(*bufio.Writer)
+		buffer.Reset(g.ResponseWriter)
+
+		if writer, err := g.conf.newWriter(g.encoding, g.level(), buffer); err != nil {
+			// Bad compression level, just serve unencoded response
+			buffer.Reset(nil)
+			g.conf.writers.Put(buffer)
+		} else {
+			g.w = writer
+			g.buffer = buffer
+			g.lastFlush = g.conf.clock()
+			g.ResponseWriter.Header().Set("Content-Encoding", g.encoding)
+			g.ResponseWriter.Header().Del("Content-Length")
+		}
 	}
+
 	g.ResponseWriter.WriteHeader(code)
 }
 
+// acquireSlot reserves one of the slots limiting concurrent compressions,
+// returning false if none are available.
+func (g *gzipWrapper) acquireSlot() bool {
+	if g.conf.slots == nil {
+		return true
+	}
+
+	select {
+	case g.conf.slots <- struct{}{}:
+		g.slot = true
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseSlot releases the slot reserved by acquireSlot, if any.
+func (g *gzipWrapper) releaseSlot() {
+	if g.slot {
+		g.slot = false
+		<-g.conf.slots
+	}
+}
+
+// level determines the compression level to use, based on the response's
+// content type.
+func (g *gzipWrapper) level() int {
+	contentType, _, _ := strings.Cut(g.ResponseWriter.Header().Get("Content-Type"), ";")
+	if level, ok := lookupContentType(g.conf.levelsByType, strings.TrimSpace(contentType)); ok {
+		return level
+	}
+	return g.conf.gzipLevel
+}
+
 func (g *gzipWrapper) Write(b []byte) (int, error) {
 	if !g.headers {
 		g.WriteHeader(http.StatusOK)
 	}
+	if g.buffered != nil {
+		return g.buffered.Write(b)
+	}
 	if g.w != nil {
-		return g.w.Write(b)
+		n, err := g.w.Write(b)
+		g.unflushed += n
+		if err == nil && g.flushDue() {
+			g.Flush()
+		}
+		return n, err
 	}
 	return g.ResponseWriter.Write(b)
 }
 
+// flushDue determines whether compressed data should be flushed, according to
+// WithGzipFlushBytes and WithGzipFlushInterval.
+func (g *gzipWrapper) flushDue() bool {
+	if g.conf.flushBytes > 0 && g.unflushed >= g.conf.flushBytes {
+		return true
+	}
+	return g.conf.flushInterval > 0 && g.conf.clock().Sub(g.lastFlush) >= g.conf.flushInterval
+}
+
+// ReadFrom copies data from the reader into the response, using a pooled
+// buffer if the response is being compressed.
+func (g *gzipWrapper) ReadFrom(r io.Reader) (int64, error) {
+	if !g.headers {
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.w == nil && g.buffered == nil {
+		if rf, ok := g.ResponseWriter.(io.ReaderFrom); ok {
+			return rf.ReadFrom(r)
+		}
+	}
+
+	buf := g.conf.buffers.Get().(*[]byte)
+	defer g.conf.buffers.Put(buf)
+
+	// Hide our ReadFrom method from io.CopyBuffer, or it will call it again
+	return io.CopyBuffer(struct{ io.Writer }{g}, r, *buf)
+}
+
+// Close closes the underlying compressing writer, if the response is being
+// compressed, and returns its buffer to the pool. If the response has been
+// buffered because of WithCompressionMinRatio, it is sent.
+func (g *gzipWrapper) Close() error {
+	defer g.releaseSlot()
+
+	if g.buffered != nil {
+		return g.sendBuffered()
+	}
+
+	if g.w == nil {
+		return nil
+	}
+
+	err := g.w.Close()
+	if flushErr := g.buffer.Flush(); err == nil {
+		err = flushErr
+	}
+
+	g.buffer.Reset(nil)
+	g.conf.writers.Put(g.buffer)
+	g.w = nil
+	g.buffer = nil
+	return err
+}
+
+// sendBuffered compresses the buffered response, and sends whichever of the
+// original or compressed version is appropriate.
+func (g *gzipWrapper) sendBuffered() error {
+	body := g.buffered.Bytes()
+	g.buffered = nil
+
+	if len(body) > 0 {
+		compressed := &bytes.Buffer{}
+		writer, err := g.conf.newWriter(g.encoding, g.level(), compressed)
+		if err == nil {
+			_, err = writer.Write(body)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+
+		if err == nil && float64(len(body))/float64(compressed.Len()) >= g.conf.minRatio {
+			body = compressed.Bytes()
+			g.ResponseWriter.Header().Set("Content-Encoding", g.encoding)
+			g.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		}
+	}
+
+	g.ResponseWriter.WriteHeader(g.status)
+	_, err := g.ResponseWriter.Write(body)
+	return err
+}
+
 func (g *gzipWrapper) Flush() {
+	if g.buffered != nil {
+		// Nothing can be sent until the response is complete
+		return
+	}
+
+	if g.w != nil {
+		if flusher, ok := g.w.(interface{ Flush() error }); ok {
+			flusher.Flush()
+		}
+		g.unflushed = 0
+		g.lastFlush = g.conf.clock()
+	}
+	if g.buffer != nil {
+		g.buffer.Flush()
+	}
 	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
 		flusher.Flush()
 	}
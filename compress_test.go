@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"bytes"
+	"compress/flate"
 	"compress/gzip"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -179,6 +185,222 @@ func TestCompress_WithCompressionCheck_NilCheck(t *testing.T) {
 	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
 }
 
+func TestCompress_NoCompressionHeader(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-No-Compression", "1")
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "test content", rr.Body.String())
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("X-No-Compression"))
+}
+
+func TestCompress_IdentityContentEncoding(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "identity")
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "test content", rr.Body.String())
+	assert.Empty(t, rr.Header().Values("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+}
+
+func TestCompress_RangeRequest(t *testing.T) {
+	content := strings.Repeat("0123456789", 20)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "test.txt", time.Time{}, strings.NewReader(content))
+	}))
+
+	req := httptest.NewRequest("GET", "/test.txt", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-99")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Empty(t, rr.Header().Values("Content-Encoding"))
+	assert.Equal(t, "bytes", rr.Header().Get("Accept-Ranges"))
+	assert.Equal(t, "bytes 0-99/200", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "100", rr.Header().Get("Content-Length"))
+	assert.Equal(t, content[:100], rr.Body.String())
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+}
+
+func TestCompress_RangeRequestEnabled(t *testing.T) {
+	handler := Compress(WithCompressionDisableForRange(false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-99")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_PartialContent(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Range", "bytes 0-11/100")
+		w.Header().Set("Content-Length", "12")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Empty(t, rr.Header().Values("Content-Encoding"))
+	assert.Equal(t, "bytes 0-11/100", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "12", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "test content", rr.Body.String())
+}
+
+func TestCompress_CustomNoCompressionHeader(t *testing.T) {
+	handler := Compress(WithNoCompressionHeader("X-Skip-Gzip"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Skip-Gzip", "true")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "test content", rr.Body.String())
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("X-Skip-Gzip"))
+}
+
+func TestCompress_FlateDictionary(t *testing.T) {
+	dictionary := []byte(`{"id":,"name":"","status":"active"}`)
+	handler := Compress(WithFlateDictionary(dictionary))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":1,"name":"test","status":"active"}`))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "deflate", rr.Header().Get("Content-Encoding"))
+
+	reader := flate.NewReaderDict(rr.Body, dictionary)
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"name":"test","status":"active"}`, string(decompressed))
+}
+
+func TestCompress_FlateDictionaryNotAccepted(t *testing.T) {
+	handler := Compress(WithFlateDictionary([]byte("dictionary")))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_DeflateWithoutDictionary(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "deflate")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "test content", rr.Body.String())
+}
+
+func TestCompress_ReadFrom(t *testing.T) {
+	content := strings.Repeat("test content ", 10000)
+	handler := Compress(WithCompressBufferSize(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Wrap the reader so it doesn't implement io.WriterTo
+		_, err := io.Copy(w, struct{ io.Reader }{strings.NewReader(content)})
+		assert.NoError(t, err)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(decompressed))
+}
+
+func TestCompress_ReadFromUncompressed(t *testing.T) {
+	content := strings.Repeat("test content ", 10000)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.Copy(w, struct{ io.Reader }{strings.NewReader(content)})
+		assert.NoError(t, err)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, content, rr.Body.String())
+}
+
+func TestCompress_NoBodyWritten(t *testing.T) {
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Body.Bytes())
+}
+
 func TestParseEncodings(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -254,3 +476,419 @@ func TestParseEncodings(t *testing.T) {
 		})
 	}
 }
+
+func TestCompress_Vary(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CompressOption
+		existing []string
+		expected []string
+	}{
+		{"default", nil, nil, []string{"Accept-Encoding"}},
+		{"existing preserved", nil, []string{"Accept"}, []string{"Accept", "Accept-Encoding"}},
+		{"existing not duplicated", nil, []string{"Accept, accept-encoding"}, []string{"Accept, accept-encoding"}},
+		{"wildcard", nil, []string{"*"}, []string{"*"}},
+		{"extra names", []CompressOption{WithVary("Accept", "Cookie")}, nil, []string{"Accept-Encoding", "Accept", "Cookie"}},
+		{"extra names merged", []CompressOption{WithVary("Accept", "Cookie")}, []string{"Accept"}, []string{"Accept", "Accept-Encoding", "Cookie"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, v := range tt.existing {
+					w.Header().Add("Vary", v)
+				}
+				w.Write([]byte("test content"))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.expected, rr.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestCompress_MinLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength string
+		compressed    bool
+	}{
+		{"small content length", "10", false},
+		{"content length at minimum", "100", true},
+		{"large content length", "1000", true},
+		{"no content length", "", true},
+		{"invalid content length", "invalid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Repeat("a", 1000)
+			handler := Compress(WithMinLength(100))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentLength != "" {
+					w.Header().Set("Content-Length", tt.contentLength)
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(content))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if tt.compressed {
+				assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+				assert.Empty(t, rr.Header().Get("Content-Length"))
+
+				reader, err := gzip.NewReader(rr.Body)
+				require.NoError(t, err)
+				decompressed, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, content, string(decompressed))
+			} else {
+				assert.Empty(t, rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, tt.contentLength, rr.Header().Get("Content-Length"))
+				assert.Equal(t, content, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompress_ExcludePaths(t *testing.T) {
+	tests := []struct {
+		path       string
+		compressed bool
+	}{
+		{"/download/file.zip", false},
+		{"/metrics", false},
+		{"/api/data", true},
+		{"/downloads", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			handler := Compress(WithCompressExcludePaths("/download/", "/metrics"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test content"))
+			}))
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if tt.compressed {
+				assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Content-Encoding"))
+				assert.Empty(t, rr.Header().Get("Vary"))
+				assert.Equal(t, "test content", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompress_GzipLevelByType(t *testing.T) {
+	content := strings.Repeat("the quick brown fox jumps over the lazy dog ", 100)
+	levels := map[string]int{
+		"text/*":    gzip.BestCompression,
+		"image/png": gzip.NoCompression,
+		"font/woff": 42,
+	}
+
+	tests := []struct {
+		contentType string
+		compressed  bool
+		small       bool
+	}{
+		{"text/html; charset=utf-8", true, true},
+		{"image/png", true, false},
+		{"application/json", true, true},
+		{"font/woff", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			handler := Compress(WithGzipLevelByType(levels))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(content))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if !tt.compressed {
+				// Invalid level, served uncompressed
+				assert.Empty(t, rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, content, rr.Body.String())
+				return
+			}
+
+			assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+			if tt.small {
+				assert.Less(t, rr.Body.Len(), len(content)/10)
+			} else {
+				assert.Greater(t, rr.Body.Len(), len(content))
+			}
+
+			reader, err := gzip.NewReader(rr.Body)
+			require.NoError(t, err)
+			decompressed, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(decompressed))
+		})
+	}
+}
+
+func TestCompress_Informational(t *testing.T) {
+	informational, res, body := serveWithInformational(t, Compress()(earlyHintsHandler), http.Header{"Accept-Encoding": {"gzip"}})
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, "</style.css>; rel=preload", informational[http.StatusEarlyHints].Get("Link"))
+	assert.Empty(t, informational[http.StatusEarlyHints].Get("Content-Encoding"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(strings.NewReader(body))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "test content", string(decompressed))
+}
+
+func TestCompress_MinRatio(t *testing.T) {
+	random := make([]byte, 4096)
+	_, err := rand.New(rand.NewSource(1)).Read(random)
+	require.NoError(t, err)
+	compressible := []byte(strings.Repeat("a", 4096))
+
+	tests := []struct {
+		name       string
+		ratio      float64
+		content    []byte
+		compressed bool
+	}{
+		{"incompressible", 1.1, random, false},
+		{"compressible", 1.1, compressible, true},
+		{"ratio not met", 10000, compressible, false},
+		{"empty", 1.1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(WithCompressionMinRatio(tt.ratio))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.WriteHeader(http.StatusCreated)
+				w.Write(tt.content)
+				w.(http.Flusher).Flush()
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code)
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+
+			if tt.compressed {
+				assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, strconv.Itoa(rr.Body.Len()), rr.Header().Get("Content-Length"))
+
+				reader, err := gzip.NewReader(rr.Body)
+				require.NoError(t, err)
+				decompressed, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				assert.Equal(t, tt.content, decompressed)
+			} else {
+				assert.Empty(t, rr.Header().Get("Content-Encoding"))
+				assert.Equal(t, len(tt.content), rr.Body.Len())
+				assert.Equal(t, string(tt.content), rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompress_MinRatioReadFrom(t *testing.T) {
+	content := strings.Repeat("a", 4096)
+	handler := Compress(WithCompressionMinRatio(1.1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, strings.NewReader(content))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(decompressed))
+}
+
+func BenchmarkCompress_LargeBody(b *testing.B) {
+	content := strings.Repeat("benchmark content ", 100000)
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Wrap the reader so it doesn't implement io.WriterTo, forcing a copy
+		io.Copy(w, struct{ io.Reader }{strings.NewReader(content)})
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func withCompressClock(clock func() time.Time) CompressOption {
+	return func(config *compressConfig) {
+		config.clock = clock
+	}
+}
+
+// decompressPartial decompresses as much of the gzip data as is available.
+func decompressPartial(t *testing.T, data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	decompressed, _ := io.ReadAll(reader)
+	return string(decompressed)
+}
+
+func TestCompress_Flush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	var flushed string
+	handler := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		flushed = decompressPartial(t, rr.Body.Bytes())
+		w.Write([]byte(" world"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "hello", flushed)
+	assert.Equal(t, "hello world", decompressPartial(t, rr.Body.Bytes()))
+}
+
+func TestCompress_FlushBytes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	var readable []string
+	handler := Compress(WithGzipFlushBytes(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range []string{"hello", "world!", "more", "content"} {
+			w.Write([]byte(s))
+			readable = append(readable, decompressPartial(t, rr.Body.Bytes()))
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{"", "helloworld!", "helloworld!", "helloworld!morecontent"}, readable)
+	assert.Equal(t, "helloworld!morecontent", decompressPartial(t, rr.Body.Bytes()))
+}
+
+func TestCompress_FlushInterval(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	rr := httptest.NewRecorder()
+	var readable []string
+	handler := Compress(
+		WithGzipFlushInterval(time.Second),
+		withCompressClock(func() time.Time { return now }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range []string{"one", "two", "three", "four"} {
+			w.Write([]byte(s))
+			readable = append(readable, decompressPartial(t, rr.Body.Bytes()))
+			now = now.Add(600 * time.Millisecond)
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{"", "", "onetwothree", "onetwothree"}, readable)
+}
+
+func TestCompress_MaxConcurrentCompressions(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := Compress(WithMaxConcurrentCompressions(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+	}))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() {
+		slow <- request("/slow")
+	}()
+	<-started
+
+	concurrent := request("/fast")
+	close(release)
+	first := <-slow
+	after := request("/fast")
+
+	assert.Equal(t, "gzip", first.Header().Get("Content-Encoding"))
+	assert.Empty(t, concurrent.Header().Get("Content-Encoding"))
+	assert.Equal(t, "test content", concurrent.Body.String())
+	assert.Equal(t, "Accept-Encoding", concurrent.Header().Get("Vary"))
+	assert.Equal(t, "gzip", after.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_MaxConcurrentCompressionsReleasedOnPanic(t *testing.T) {
+	handler := Compress(WithMaxConcurrentCompressions(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+	}))
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Panics(t, func() { request("/panic") })
+	assert.Equal(t, "gzip", request("/ok").Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ConditionalShortCircuit is a middleware that handles conditional GET and
+// HEAD requests without calling the next handler, for content whose version
+// can be determined cheaply (e.g. from a database timestamp).
+//
+// The given function is called before the next handler to compute the current
+// ETag for the request. If it matches the request's If-None-Match header, a
+// 304 Not Modified response is sent and the next handler is not called.
+// Otherwise, the ETag header is set on the response and the request is passed
+// on as normal.
+//
+// The function should return an entity tag such as `"v1"` or `W/"v1"`; if the
+// value isn't quoted, quotes will be added. If it returns an empty string, the
+// request is passed to the next handler unmodified.
+func ConditionalShortCircuit(etagFunc func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			etag := etagFunc(r)
+			if etag == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+				etag = `"` + etag + `"`
+			}
+
+			w.Header().Set("ETag", etag)
+			if etagMatches(r.Header.Values("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// etagMatches determines whether any of the entity tags in the given
+// If-None-Match header values matches the etag, using weak comparison.
+func etagMatches(values []string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range values {
+		for _, candidate := range strings.Split(v, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
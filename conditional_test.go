@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalShortCircuit(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		etag           string
+		ifNoneMatch    []string
+		expectedStatus int
+		expectedETag   string
+		expectCalled   bool
+	}{
+		{"match", "GET", `"v1"`, []string{`"v1"`}, http.StatusNotModified, `"v1"`, false},
+		{"mismatch", "GET", `"v2"`, []string{`"v1"`}, http.StatusOK, `"v2"`, true},
+		{"no header", "GET", `"v1"`, nil, http.StatusOK, `"v1"`, true},
+		{"match in list", "GET", `"v2"`, []string{`"v1", "v2"`}, http.StatusNotModified, `"v2"`, false},
+		{"match in multiple headers", "GET", `"v2"`, []string{`"v1"`, `"v2"`}, http.StatusNotModified, `"v2"`, false},
+		{"wildcard", "GET", `"v1"`, []string{"*"}, http.StatusNotModified, `"v1"`, false},
+		{"weak match", "GET", `W/"v1"`, []string{`"v1"`}, http.StatusNotModified, `W/"v1"`, false},
+		{"weak request", "GET", `"v1"`, []string{`W/"v1"`}, http.StatusNotModified, `"v1"`, false},
+		{"unquoted etag", "GET", "v1", []string{`"v1"`}, http.StatusNotModified, `"v1"`, false},
+		{"head", "HEAD", `"v1"`, []string{`"v1"`}, http.StatusNotModified, `"v1"`, false},
+		{"post", "POST", `"v1"`, []string{`"v1"`}, http.StatusOK, "", true},
+		{"empty etag", "GET", "", []string{"*"}, http.StatusOK, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := ConditionalShortCircuit(func(r *http.Request) string {
+				return tt.etag
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			for _, v := range tt.ifNoneMatch {
+				req.Header.Add("If-None-Match", v)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedETag, rr.Header().Get("ETag"))
+			assert.Equal(t, tt.expectCalled, called)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import "net/http"
+
+type connectionControlConfig struct {
+	closeWhen func(*http.Request) bool
+}
+
+type ConnectionControlOption func(*connectionControlConfig)
+
+// WithCloseWhen sets a predicate that determines whether the connection should
+// be closed after responding to a request, for example when the server is
+// shedding load.
+func WithCloseWhen(predicate func(*http.Request) bool) ConnectionControlOption {
+	return func(config *connectionControlConfig) {
+		config.closeWhen = predicate
+	}
+}
+
+// ConnectionControl is a middleware that asks clients to close their
+// connection after the current request, which can help redistribute load
+// across servers during overload. Use WithCloseWhen to specify when this
+// should happen; by default connections are never closed.
+//
+// This works by setting a `Connection: close` response header before calling
+// the next handler. For HTTP/1.x, net/http will send the header and close the
+// connection once the response is complete.
+//
+// HTTP/2 doesn't permit the Connection header. The net/http HTTP/2 server
+// strips it and instead sends a GOAWAY frame, gracefully closing the
+// connection once in-flight requests have finished. Other servers, and any
+// proxies in front of this one, may simply ignore it.
+func ConnectionControl(opts ...ConnectionControlOption) func(http.Handler) http.Handler {
+	config := &connectionControlConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.closeWhen != nil && config.closeWhen(r) {
+				w.Header().Set("Connection", "close")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
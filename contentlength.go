@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrContentLengthMismatch is returned when reading a request body wrapped by
+// the VerifyContentLength middleware if it doesn't match the length declared
+// in the Content-Length header.
+var ErrContentLengthMismatch = errors.New("middleware: request body length does not match Content-Length")
+
+// VerifyContentLength checks that the request body contains exactly the
+// number of bytes declared in its Content-Length header, to detect truncated
+// uploads.
+//
+// The body is checked as it is read: if it ends early, or continues past the
+// declared length, reading it will return ErrContentLengthMismatch and the
+// response will be replaced with a 400 response. Because the body is
+// streamed, a mismatch can only be detected once the handler has read up to
+// the point of the discrepancy, and the response can't be replaced if the
+// handler has already started writing it.
+//
+// Chain this middleware with ErrorHandler to customise this.
+func VerifyContentLength() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || r.ContentLength < 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := &contentLengthReader{
+				ReadCloser: r.Body,
+				expected:   r.ContentLength,
+			}
+			r.Body = body
+
+			wrapped := &contentLengthWrapper{
+				ResponseWriter: w,
+				body:           body,
+			}
+			next.ServeHTTP(wrapped, r)
+
+			if !wrapped.headers && body.mismatch {
+				wrapped.WriteHeader(http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+type contentLengthReader struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+	mismatch bool
+}
+
+func (c *contentLengthReader) Read(p []byte) (int, error) {
+	if c.mismatch {
+		return 0, ErrContentLengthMismatch
+	}
+
+	n, err := c.ReadCloser.Read(p)
+	c.read += int64(n)
+
+	if c.read > c.expected {
+		c.mismatch = true
+	} else if c.read < c.expected && (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) {
+		c.mismatch = true
+	}
+
+	if c.mismatch {
+		return 0, ErrContentLengthMismatch
+	}
+	return n, err
+}
+
+type contentLengthWrapper struct {
+	http.ResponseWriter
+	body    *contentLengthReader
+	drop    bool
+	headers bool
+}
+
+func (c *contentLengthWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	c.headers = true
+	if c.body.mismatch {
+		c.drop = true
+		http.Error(c.ResponseWriter, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *contentLengthWrapper) Write(b []byte) (int, error) {
+	if !c.headers {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if c.drop {
+		return len(b), nil
+	}
+
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *contentLengthWrapper) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
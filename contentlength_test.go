@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyContentLength_Matching(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := VerifyContentLength()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello world"))
+	req.ContentLength = 11
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, readErr)
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "ok", rr.Body.String())
+}
+
+func TestVerifyContentLength_Truncated(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := VerifyContentLength()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello"))
+	req.ContentLength = 11
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrContentLengthMismatch)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "read failed")
+}
+
+func TestVerifyContentLength_TooLong(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := VerifyContentLength()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello world"))
+	req.ContentLength = 5
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrContentLengthMismatch)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestVerifyContentLength_UnreadBody(t *testing.T) {
+	handler := VerifyContentLength()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello"))
+	req.ContentLength = 11
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+}
+
+func TestVerifyContentLength_ResponseAlreadyStarted(t *testing.T) {
+	var readErr error
+	handler := VerifyContentLength()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, readErr = io.ReadAll(r.Body)
+		w.Write([]byte("done"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader("hello"))
+	req.ContentLength = 11
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrContentLengthMismatch)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "done", rr.Body.String())
+}
+
+func TestVerifyContentLength_NoBody(t *testing.T) {
+	called := false
+	handler := VerifyContentLength()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestVerifyContentLength_Server(t *testing.T) {
+	readErr := make(chan error, 1)
+	handler := VerifyContentLength()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 11\r\n\r\nhello"))
+	require.NoError(t, err)
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+
+	select {
+	case err := <-readErr:
+		assert.ErrorIs(t, err, ErrContentLengthMismatch)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not finish reading the body")
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import "net/http"
+
+// DefaultContentType is a middleware that sets the Content-Type header of
+// responses to the given value, if the handler hasn't set one itself.
+//
+// Unlike the content sniffing performed by net/http, this always results in
+// the declared type being used.
+func DefaultContentType(contentType string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&defaultContentTypeWrapper{
+				ResponseWriter: w,
+				contentType:    contentType,
+			}, r)
+		})
+	}
+}
+
+type defaultContentTypeWrapper struct {
+	http.ResponseWriter
+	contentType string
+	headers     bool
+}
+
+func (d *defaultContentTypeWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		d.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	d.headers = true
+	if _, ok := d.ResponseWriter.Header()["Content-Type"]; !ok {
+		d.ResponseWriter.Header().Set("Content-Type", d.contentType)
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *defaultContentTypeWrapper) Write(b []byte) (int, error) {
+	if !d.headers {
+		d.WriteHeader(http.StatusOK)
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+func (d *defaultContentTypeWrapper) Flush() {
+	if flusher, ok := d.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultContentType_NotSet(t *testing.T) {
+	handler := DefaultContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html></html>"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "<html></html>", rr.Body.String())
+}
+
+func TestDefaultContentType_ExplicitTypePreserved(t *testing.T) {
+	handler := DefaultContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+}
+
+func TestDefaultContentType_WriteHeader(t *testing.T) {
+	handler := DefaultContentType("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+}
+
+func TestDefaultContentType_Informational(t *testing.T) {
+	informational, res, _ := serveWithInformational(t, DefaultContentType("application/json")(earlyHintsHandler), nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Empty(t, informational[http.StatusEarlyHints].Get("Content-Type"))
+	assert.Equal(t, "text/html", res.Header.Get("Content-Type"))
+}
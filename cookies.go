@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+type cookieDefaultsConfig struct {
+	sameSite http.SameSite
+	secure   bool
+	httpOnly bool
+}
+
+type CookieDefaultsOption func(*cookieDefaultsConfig)
+
+// WithCookieSameSite sets the SameSite attribute added to cookies that don't
+// specify one. Defaults to http.SameSiteLaxMode. Use http.SameSiteDefaultMode
+// to leave the attribute unset.
+func WithCookieSameSite(mode http.SameSite) CookieDefaultsOption {
+	return func(config *cookieDefaultsConfig) {
+		config.sameSite = mode
+	}
+}
+
+// WithCookieSecure sets whether the Secure attribute should be added to
+// cookies set in responses to requests made over TLS. True by default.
+func WithCookieSecure(secure bool) CookieDefaultsOption {
+	return func(config *cookieDefaultsConfig) {
+		config.secure = secure
+	}
+}
+
+// WithCookieHTTPOnly sets whether the HttpOnly attribute should be added to
+// cookies. True by default.
+func WithCookieHTTPOnly(httpOnly bool) CookieDefaultsOption {
+	return func(config *cookieDefaultsConfig) {
+		config.httpOnly = httpOnly
+	}
+}
+
+// CookieDefaults adds default attributes to any cookies set by the next
+// handler, if they aren't already present. By default, cookies are given the
+// attributes "SameSite=Lax", "HttpOnly", and (if the request was made over
+// TLS) "Secure".
+//
+// Attributes that are already present on a cookie are never changed, so
+// handlers can still explicitly opt out of the defaults (e.g. by setting
+// "SameSite=None" for a cookie that must be sent cross-site).
+func CookieDefaults(opts ...CookieDefaultsOption) func(http.Handler) http.Handler {
+	config := &cookieDefaultsConfig{
+		sameSite: http.SameSiteLaxMode,
+		secure:   true,
+		httpOnly: true,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &cookieDefaultsWrapper{
+				ResponseWriter: w,
+				conf:           config,
+				tls:            r.TLS != nil,
+			}
+			next.ServeHTTP(wrapped, r)
+
+			if !wrapped.headers {
+				// The server will write the headers once we return
+				wrapped.applyDefaults()
+			}
+		})
+	}
+}
+
+type cookieDefaultsWrapper struct {
+	http.ResponseWriter
+	conf    *cookieDefaultsConfig
+	tls     bool
+	headers bool
+}
+
+func (c *cookieDefaultsWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		c.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	c.headers = true
+	c.applyDefaults()
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *cookieDefaultsWrapper) Write(b []byte) (int, error) {
+	if !c.headers {
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *cookieDefaultsWrapper) Flush() {
+	if !c.headers {
+		c.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// applyDefaults adds any missing attributes to each Set-Cookie header.
+func (c *cookieDefaultsWrapper) applyDefaults() {
+	cookies := c.ResponseWriter.Header()["Set-Cookie"]
+	for i := range cookies {
+		cookies[i] = c.conf.apply(cookies[i], c.tls)
+	}
+}
+
+// apply adds any missing attributes to a single Set-Cookie value.
+func (c *cookieDefaultsConfig) apply(cookie string, tls bool) string {
+	present := make(map[string]bool)
+	parts := strings.Split(cookie, ";")
+	for _, attr := range parts[1:] {
+		name, _, _ := strings.Cut(attr, "=")
+		present[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	if !present["samesite"] {
+		switch c.sameSite {
+		case http.SameSiteLaxMode:
+			cookie += "; SameSite=Lax"
+		case http.SameSiteStrictMode:
+			cookie += "; SameSite=Strict"
+		case http.SameSiteNoneMode:
+			cookie += "; SameSite=None"
+		}
+	}
+	if c.secure && tls && !present["secure"] {
+		cookie += "; Secure"
+	}
+	if c.httpOnly && !present["httponly"] {
+		cookie += "; HttpOnly"
+	}
+	return cookie
+}
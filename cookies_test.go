@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveCookies(t *testing.T, handler func(http.Handler) http.Handler, useTLS bool, cookies ...string) []string {
+	h := handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := range cookies {
+			w.Header().Add("Set-Cookie", cookies[i])
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	if useTLS {
+		req.TLS = &tls.ConnectionState{}
+	}
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+
+	assert.Equal(t, "ok", rr.Body.String())
+	return rr.Header().Values("Set-Cookie")
+}
+
+func TestCookieDefaults_AddsMissingAttributes(t *testing.T) {
+	cookies := serveCookies(t, CookieDefaults(), false, "session=abc123; Path=/")
+
+	assert.Equal(t, []string{"session=abc123; Path=/; SameSite=Lax; HttpOnly"}, cookies)
+}
+
+func TestCookieDefaults_SecureOverTLS(t *testing.T) {
+	cookies := serveCookies(t, CookieDefaults(), true, "session=abc123")
+
+	assert.Equal(t, []string{"session=abc123; SameSite=Lax; Secure; HttpOnly"}, cookies)
+}
+
+func TestCookieDefaults_KeepsExplicitAttributes(t *testing.T) {
+	cookies := serveCookies(t, CookieDefaults(), true,
+		"session=abc123; samesite=Strict; secure; HTTPONLY",
+		"tracking=xyz; SameSite=None; Secure",
+		"theme=dark; Max-Age=3600",
+	)
+
+	assert.Equal(t, []string{
+		"session=abc123; samesite=Strict; secure; HTTPONLY",
+		"tracking=xyz; SameSite=None; Secure; HttpOnly",
+		"theme=dark; Max-Age=3600; SameSite=Lax; Secure; HttpOnly",
+	}, cookies)
+}
+
+func TestCookieDefaults_ValueContainingAttributeNames(t *testing.T) {
+	cookies := serveCookies(t, CookieDefaults(), false, "Secure=HttpOnly")
+
+	assert.Equal(t, []string{"Secure=HttpOnly; SameSite=Lax; HttpOnly"}, cookies)
+}
+
+func TestCookieDefaults_Options(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CookieDefaultsOption
+		expected string
+	}{
+		{
+			name:     "strict",
+			opts:     []CookieDefaultsOption{WithCookieSameSite(http.SameSiteStrictMode)},
+			expected: "session=abc123; SameSite=Strict; Secure; HttpOnly",
+		},
+		{
+			name:     "no same site",
+			opts:     []CookieDefaultsOption{WithCookieSameSite(http.SameSiteDefaultMode)},
+			expected: "session=abc123; Secure; HttpOnly",
+		},
+		{
+			name:     "no secure",
+			opts:     []CookieDefaultsOption{WithCookieSecure(false)},
+			expected: "session=abc123; SameSite=Lax; HttpOnly",
+		},
+		{
+			name:     "no http only",
+			opts:     []CookieDefaultsOption{WithCookieHTTPOnly(false)},
+			expected: "session=abc123; SameSite=Lax; Secure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookies := serveCookies(t, CookieDefaults(tt.opts...), true, "session=abc123")
+
+			assert.Equal(t, []string{tt.expected}, cookies)
+		})
+	}
+}
+
+func TestCookieDefaults_NoBody(t *testing.T) {
+	handler := CookieDefaults()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, "session=abc123; SameSite=Lax; HttpOnly", rr.Header().Get("Set-Cookie"))
+}
+
+func TestCookieDefaults_ExplicitWriteHeader(t *testing.T) {
+	handler := CookieDefaults()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		w.WriteHeader(http.StatusFound)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "session=abc123; SameSite=Lax; HttpOnly", rr.Header().Get("Set-Cookie"))
+}
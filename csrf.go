@@ -1,6 +1,12 @@
 package middleware
 
-import "net/http"
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
 
 // CrossOriginProtection is a middleware that denies unsafe requests that
 // originated from a different origin, to defend against CSRF attacks.
@@ -29,3 +35,121 @@ func CrossOriginProtection() func(http.Handler) http.Handler {
 		})
 	}
 }
+
+type csrfTokenKey struct{}
+
+type csrfTokenConfig struct {
+	cookieName string
+	headerName string
+	sameSite   http.SameSite
+	secure     bool
+}
+
+type CSRFTokenOption func(*csrfTokenConfig)
+
+// WithCSRFCookieName sets the name of the cookie used to store the CSRF token.
+// Defaults to "csrf_token".
+func WithCSRFCookieName(name string) CSRFTokenOption {
+	return func(config *csrfTokenConfig) {
+		config.cookieName = name
+	}
+}
+
+// WithCSRFHeaderName sets the name of the header used to expose the CSRF token
+// on responses, and that must contain the token on unsafe requests. Defaults to
+// "X-CSRF-Token".
+func WithCSRFHeaderName(name string) CSRFTokenOption {
+	return func(config *csrfTokenConfig) {
+		config.headerName = name
+	}
+}
+
+// WithCSRFSameSite sets the SameSite attribute of the CSRF token cookie.
+// Defaults to http.SameSiteLaxMode.
+func WithCSRFSameSite(sameSite http.SameSite) CSRFTokenOption {
+	return func(config *csrfTokenConfig) {
+		config.sameSite = sameSite
+	}
+}
+
+// WithCSRFSecure sets whether the CSRF token cookie has the Secure attribute.
+// Defaults to true.
+func WithCSRFSecure(secure bool) CSRFTokenOption {
+	return func(config *csrfTokenConfig) {
+		config.secure = secure
+	}
+}
+
+// CSRFToken is a middleware that defends against CSRF attacks using a
+// "double-submit" token.
+//
+// On GET, HEAD and OPTIONS requests, it ensures that the client has a cookie
+// containing a random token, and exposes the token in a response header. The
+// token is also available to handlers using CSRFTokenValue.
+//
+// Any other request must include a header containing the same token as the
+// cookie, otherwise it is denied with a 403 response with no body. Chain this
+// middleware with ErrorHandler to customise this.
+func CSRFToken(opts ...CSRFTokenOption) func(http.Handler) http.Handler {
+	config := &csrfTokenConfig{
+		cookieName: "csrf_token",
+		headerName: "X-CSRF-Token",
+		sameSite:   http.SameSiteLaxMode,
+		secure:     true,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := ""
+			if cookie, err := r.Cookie(config.cookieName); err == nil {
+				token = cookie.Value
+			}
+
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				if token == "" {
+					token = newCSRFToken()
+					http.SetCookie(w, &http.Cookie{
+						Name:     config.cookieName,
+						Value:    token,
+						Path:     "/",
+						SameSite: config.sameSite,
+						Secure:   config.secure,
+					})
+				}
+
+				w.Header().Set(config.headerName, token)
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token)))
+				return
+			}
+
+			header := r.Header.Get(config.headerName)
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(header)) != 1 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey{}, token)))
+		})
+	}
+}
+
+// CSRFTokenValue returns the CSRF token for the request, as set or validated by
+// the CSRFToken middleware. Returns an empty string if the middleware was not
+// used.
+func CSRFTokenValue(r *http.Request) string {
+	if t, ok := r.Context().Value(csrfTokenKey{}).(string); ok {
+		return t
+	}
+	return ""
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCrossOriginProtection(t *testing.T) {
@@ -68,3 +69,105 @@ func TestCrossOriginProtection(t *testing.T) {
 		})
 	}
 }
+
+func TestCSRFToken_SetOnGet(t *testing.T) {
+	var token string
+	handler := CSRFToken()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = CSRFTokenValue(r)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "csrf_token", cookies[0].Name)
+	assert.NotEmpty(t, cookies[0].Value)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	assert.Equal(t, cookies[0].Value, token)
+	assert.Equal(t, cookies[0].Value, rr.Header().Get("X-CSRF-Token"))
+}
+
+func TestCSRFToken_ExistingCookieOnGet(t *testing.T) {
+	handler := CSRFToken()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "existing"})
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Result().Cookies())
+	assert.Equal(t, "existing", rr.Header().Get("X-CSRF-Token"))
+}
+
+func TestCSRFToken_ValidateOnPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		cookie         string
+		header         string
+		expectedStatus int
+	}{
+		{"matching token", "token", "token", http.StatusOK},
+		{"mismatched token", "token", "other", http.StatusForbidden},
+		{"missing header", "token", "", http.StatusForbidden},
+		{"missing cookie", "", "token", http.StatusForbidden},
+		{"missing both", "", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := CSRFToken()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest("POST", "/test", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, called)
+		})
+	}
+}
+
+func TestCSRFToken_CustomOptions(t *testing.T) {
+	handler := CSRFToken(
+		WithCSRFCookieName("xsrf"),
+		WithCSRFHeaderName("X-XSRF-Token"),
+		WithCSRFSameSite(http.SameSiteStrictMode),
+		WithCSRFSecure(false),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "xsrf", cookies[0].Name)
+	assert.False(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	assert.Equal(t, cookies[0].Value, rr.Header().Get("X-XSRF-Token"))
+
+	req = httptest.NewRequest("POST", "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "xsrf", Value: cookies[0].Value})
+	req.Header.Set("X-XSRF-Token", cookies[0].Value)
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errMalformedDeadline = errors.New("middleware: malformed deadline")
+
+type deadlineFromHeaderConfig struct {
+	headerName      string
+	parser          func(string) (time.Duration, error)
+	maxTimeout      time.Duration
+	rejectMalformed bool
+}
+
+type DeadlineFromHeaderOption func(*deadlineFromHeaderConfig)
+
+// WithDeadlineHeader sets the name of the header that DeadlineFromHeader will
+// read the timeout from. Defaults to "X-Request-Timeout".
+func WithDeadlineHeader(headerName string) DeadlineFromHeaderOption {
+	return func(config *deadlineFromHeaderConfig) {
+		config.headerName = headerName
+	}
+}
+
+// WithDeadlineParser sets the function used to parse the header value into a
+// duration. By default, values may either be a whole number of seconds (e.g.
+// "30"), or a duration as accepted by time.ParseDuration (e.g. "1m30s").
+// Values that would overflow a time.Duration are treated as malformed.
+func WithDeadlineParser(parser func(string) (time.Duration, error)) DeadlineFromHeaderOption {
+	return func(config *deadlineFromHeaderConfig) {
+		config.parser = parser
+	}
+}
+
+// WithGRPCTimeout configures DeadlineFromHeader to read the "Grpc-Timeout"
+// header used by gRPC clients. Its values are up to 8 digits followed by a
+// unit: "H" (hours), "M" (minutes), "S" (seconds), "m" (milliseconds), "u"
+// (microseconds) or "n" (nanoseconds), e.g. "100m" for 100 milliseconds.
+func WithGRPCTimeout() DeadlineFromHeaderOption {
+	return func(config *deadlineFromHeaderConfig) {
+		config.headerName = "Grpc-Timeout"
+		config.parser = parseGRPCTimeout
+	}
+}
+
+// WithMaxDeadline caps the timeout that clients can request. Timeouts longer
+// than the maximum will be reduced to it. By default, there is no maximum.
+func WithMaxDeadline(max time.Duration) DeadlineFromHeaderOption {
+	return func(config *deadlineFromHeaderConfig) {
+		config.maxTimeout = max
+	}
+}
+
+// WithRejectMalformedDeadline sets whether requests with a malformed timeout
+// header should be rejected with a 400 Bad Request response. If false (the
+// default), malformed values are ignored and the request proceeds without a
+// deadline.
+func WithRejectMalformedDeadline(reject bool) DeadlineFromHeaderOption {
+	return func(config *deadlineFromHeaderConfig) {
+		config.rejectMalformed = reject
+	}
+}
+
+// DeadlineFromHeader is a middleware that reads a timeout requested by the
+// client from a header, and applies it as a deadline on the request's context.
+//
+// Requests without the header are passed through unchanged. Values that can't
+// be parsed, or that aren't positive, are treated as malformed.
+func DeadlineFromHeader(opts ...DeadlineFromHeaderOption) func(http.Handler) http.Handler {
+	config := &deadlineFromHeaderConfig{
+		headerName: "X-Request-Timeout",
+		parser:     parseDeadline,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value := r.Header.Get(config.headerName)
+			if value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			timeout, err := config.parser(value)
+			if err != nil || timeout <= 0 {
+				if config.rejectMalformed {
+					http.Error(w, "Malformed request timeout", http.StatusBadRequest)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if config.maxTimeout > 0 && timeout > config.maxTimeout {
+				timeout = config.maxTimeout
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func parseDeadline(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return scaleDeadline(seconds, time.Second)
+	}
+	return time.ParseDuration(value)
+}
+
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+func parseGRPCTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, errMalformedDeadline
+	}
+
+	unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+	if !ok {
+		return 0, errMalformedDeadline
+	}
+
+	digits := value[:len(value)-1]
+	for i := range digits {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, errMalformedDeadline
+		}
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return scaleDeadline(n, unit)
+}
+
+// scaleDeadline multiplies n by unit, returning an error if the result can't
+// be represented as a time.Duration.
+func scaleDeadline(n int64, unit time.Duration) (time.Duration, error) {
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, errMalformedDeadline
+	}
+	return time.Duration(n) * unit, nil
+}
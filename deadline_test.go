@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineFromHeader_ValidHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"Seconds", "30", time.Second * 30},
+		{"Duration", "1m30s", time.Second * 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			handler := DeadlineFromHeader()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, hasDeadline = r.Context().Deadline()
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-Request-Timeout", tt.value)
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.True(t, hasDeadline)
+			assert.WithinDuration(t, start.Add(tt.expected), deadline, time.Second)
+		})
+	}
+}
+
+func TestDeadlineFromHeader_MissingHeader(t *testing.T) {
+	var hasDeadline bool
+	handler := DeadlineFromHeader()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.False(t, hasDeadline)
+}
+
+func TestDeadlineFromHeader_MalformedIgnored(t *testing.T) {
+	tests := []string{"soon", "-5", "0"}
+
+	for _, value := range tests {
+		t.Run(value, func(t *testing.T) {
+			called := false
+			var hasDeadline bool
+			handler := DeadlineFromHeader()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				_, hasDeadline = r.Context().Deadline()
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-Request-Timeout", value)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.True(t, called)
+			assert.False(t, hasDeadline)
+		})
+	}
+}
+
+func TestDeadlineFromHeader_MalformedRejected(t *testing.T) {
+	called := false
+	handler := DeadlineFromHeader(WithRejectMalformedDeadline(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-Timeout", "soon")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestDeadlineFromHeader_OverMaximum(t *testing.T) {
+	var deadline time.Time
+	handler := DeadlineFromHeader(WithMaxDeadline(time.Second * 10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-Timeout", "3600")
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+
+	assert.WithinDuration(t, start.Add(time.Second*10), deadline, time.Second)
+}
+
+func TestDeadlineFromHeader_CustomHeaderAndParser(t *testing.T) {
+	var deadline time.Time
+	handler := DeadlineFromHeader(
+		WithDeadlineHeader("Grpc-Timeout"),
+		WithDeadlineParser(func(s string) (time.Duration, error) {
+			return time.ParseDuration(s + "s")
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Grpc-Timeout", "20")
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+
+	assert.WithinDuration(t, start.Add(time.Second*20), deadline, time.Second)
+}
+
+func TestDeadlineFromHeader_GRPCTimeout(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"2H", 2 * time.Hour},
+		{"3M", 3 * time.Minute},
+		{"5S", 5 * time.Second},
+		{"100m", 100 * time.Millisecond},
+		{"100u", 100 * time.Microsecond},
+		{"99999999n", 99999999 * time.Nanosecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			timeout, err := parseGRPCTimeout(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, timeout)
+		})
+	}
+
+	for _, value := range []string{"", "5", "S", "5s", "-5S", "+5S", "5 S", "123456789S", "99999999H"} {
+		t.Run("malformed "+value, func(t *testing.T) {
+			_, err := parseGRPCTimeout(value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDeadlineFromHeader_WithGRPCTimeout(t *testing.T) {
+	var deadline time.Time
+	handler := DeadlineFromHeader(WithGRPCTimeout())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Grpc-Timeout", "100m")
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+
+	assert.WithinDuration(t, start.Add(100*time.Millisecond), deadline, 50*time.Millisecond)
+}
+
+func TestDeadlineFromHeader_Overflow(t *testing.T) {
+	_, err := parseDeadline("9223372037")
+	assert.Error(t, err)
+
+	timeout, err := parseDeadline("9223372036")
+	require.NoError(t, err)
+	assert.Equal(t, 9223372036*time.Second, timeout)
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+type debugConfigEntry struct {
+	Name     string         `json:"name"`
+	Settings map[string]any `json:"settings,omitempty"`
+}
+
+// DebugRegistry collects the settings that middleware have been configured
+// with, to be served by DebugConfig. Middleware that support it (such as
+// RateLimit, Compress and CacheControl) register their resolved settings when
+// they are constructed with the registry passed as an option. It is safe for
+// concurrent use.
+type DebugRegistry struct {
+	mutex   sync.Mutex
+	entries []debugConfigEntry
+}
+
+// NewDebugRegistry creates a new, empty DebugRegistry.
+func NewDebugRegistry() *DebugRegistry {
+	return &DebugRegistry{}
+}
+
+// Register adds an entry with the given name and settings to the registry.
+// Entries are listed in the order they are registered. The settings must be
+// able to be marshalled to JSON. Registering with a nil registry does nothing.
+func (d *DebugRegistry) Register(name string, settings map[string]any) {
+	if d == nil {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.entries = append(d.entries, debugConfigEntry{
+		Name:     name,
+		Settings: settings,
+	})
+}
+
+func (d *DebugRegistry) snapshot() []debugConfigEntry {
+	if d == nil {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return append([]debugConfigEntry(nil), d.entries...)
+}
+
+type debugConfigConfig struct {
+	registries []*DebugRegistry
+	entries    []debugConfigEntry
+}
+
+type DebugConfigOption func(*debugConfigConfig)
+
+// WithDebugRegistry includes the entries in the given registry in the output
+// of DebugConfig. Entries registered after DebugConfig is constructed are
+// included too.
+func WithDebugRegistry(registry *DebugRegistry) DebugConfigOption {
+	return func(config *debugConfigConfig) {
+		config.registries = append(config.registries, registry)
+	}
+}
+
+// WithDebugSettings adds an entry with the given name and settings to the
+// output of DebugConfig, for middleware that don't register with a
+// DebugRegistry. These entries are listed after those from registries, in the
+// order they are added. The settings are reported exactly as given, and must
+// be able to be marshalled to JSON.
+func WithDebugSettings(name string, settings map[string]any) DebugConfigOption {
+	return func(config *debugConfigConfig) {
+		config.entries = append(config.entries, debugConfigEntry{
+			Name:     name,
+			Settings: settings,
+		})
+	}
+}
+
+// DebugConfig is a middleware that responds to GET and HEAD requests for the
+// given path with a JSON document listing the settings of middleware
+// registered with the DebugRegistry given to WithDebugRegistry, followed by
+// any entries added with WithDebugSettings. This helps confirm which options
+// are active in a running service. All other requests are passed to the next
+// handler.
+//
+// The output may reveal details about how the service is configured, so this
+// should be restricted to trusted clients, e.g. by chaining it after
+// AllowedHosts or an authentication middleware.
+func DebugConfig(path string, opts ...DebugConfigOption) func(http.Handler) http.Handler {
+	config := &debugConfigConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			entries := []debugConfigEntry{}
+			for i := range config.registries {
+				entries = append(entries, config.registries[i].snapshot()...)
+			}
+			entries = append(entries, config.entries...)
+
+			body, err := json.Marshal(struct {
+				Middleware []debugConfigEntry `json:"middleware"`
+			}{entries})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+		})
+	}
+}
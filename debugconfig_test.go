@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugConfig(t *testing.T) {
+	handler := DebugConfig(
+		"/debug/config",
+		WithDebugSettings("Compress", map[string]any{"level": 6}),
+		WithDebugSettings("Timeout", map[string]any{"timeout": (30 * time.Second).String()}),
+		WithDebugSettings("Recover", nil),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("next"))
+	}))
+
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"middleware":[{"name":"Compress","settings":{"level":6}},{"name":"Timeout","settings":{"timeout":"30s"}},{"name":"Recover"}]}`, rr.Body.String())
+}
+
+func TestDebugConfig_Registry(t *testing.T) {
+	registry := NewDebugRegistry()
+
+	// Middleware constructed after DebugConfig are still included
+	debug := DebugConfig(
+		"/debug/config",
+		WithDebugRegistry(registry),
+		WithDebugSettings("Custom", map[string]any{"enabled": true}),
+	)
+
+	RateLimit(WithRate(10, time.Second), WithRateLimitDebugRegistry(registry))
+	Compress(WithMinLength(1024), WithVary("Origin"), WithCompressBufferSize(-1), WithCompressDebugRegistry(registry))
+	CacheControl(
+		WithCacheTimes(map[string]time.Duration{"text/html": time.Minute}),
+		WithNoTransform("image/png"),
+		WithAuthAwareCaching(func(*http.Request) bool { return false }, "private", "public"),
+		WithCacheControlDebugRegistry(registry),
+	)
+
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	rr := httptest.NewRecorder()
+
+	debug(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"middleware":[
+		{"name":"RateLimit","settings":{"tokens":10,"interval":"1s"}},
+		{"name":"Compress","settings":{
+			"gzipLevel":-1,
+			"gzipLevelByType":null,
+			"compressionCheck":false,
+			"noCompressionHeader":"X-No-Compression",
+			"flateDictionaryBytes":0,
+			"bufferSize":32768,
+			"vary":["Origin"],
+			"minLength":1024,
+			"excludePaths":null,
+			"minRatio":0,
+			"disableForRange":true,
+			"flushInterval":"0s",
+			"flushBytes":0,
+			"maxConcurrent":0
+		}},
+		{"name":"CacheControl","settings":{
+			"cacheTimes":{"text/html":"1m0s"},
+			"ageFunc":false,
+			"noTransform":["image/png"],
+			"respectRequestCacheControl":false,
+			"vary":null,
+			"expiresHeader":false,
+			"noCacheOnSetCookie":false,
+			"privateWhenAuthorized":false,
+			"staleIfError":{},
+			"authAwareCaching":{"authenticated":"private","anonymous":"public"}
+		}},
+		{"name":"Custom","settings":{"enabled":true}}
+	]}`, rr.Body.String())
+}
+
+func TestDebugRegistry_Nil(t *testing.T) {
+	var registry *DebugRegistry
+	assert.NotPanics(t, func() {
+		registry.Register("RateLimit", nil)
+		RateLimit()
+	})
+
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	rr := httptest.NewRecorder()
+
+	DebugConfig("/debug/config", WithDebugRegistry(registry))(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+	assert.JSONEq(t, `{"middleware":[]}`, rr.Body.String())
+}
+
+func TestDebugConfig_Empty(t *testing.T) {
+	handler := DebugConfig("/debug/config")(http.NotFoundHandler())
+
+	req := httptest.NewRequest("GET", "/debug/config", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"middleware":[]}`, rr.Body.String())
+}
+
+func TestDebugConfig_OtherRequests(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/"},
+		{"GET", "/debug/config/extra"},
+		{"POST", "/debug/config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			handler := DebugConfig("/debug/config", WithDebugSettings("Compress", nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("next"))
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, "next", rr.Body.String())
+		})
+	}
+}
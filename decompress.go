@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrDecompressLimit is returned when reading a request body decompressed by
+// the Decompress middleware if it exceeds the configured limits.
+var ErrDecompressLimit = errors.New("middleware: decompressed request body too large")
+
+type decompressConfig struct {
+	maxSize       int64
+	maxRatio      float64
+	ratioMinBytes int64
+}
+
+type DecompressOption func(*decompressConfig)
+
+// WithMaxDecompressedSize sets the maximum size in bytes that a request body
+// may decompress to. Defaults to 10MiB. A value of 0 disables the limit.
+func WithMaxDecompressedSize(size int64) DecompressOption {
+	return func(config *decompressConfig) {
+		config.maxSize = size
+	}
+}
+
+// WithMaxDecompressRatio sets the maximum ratio of decompressed bytes to
+// compressed bytes allowed in a request body. The ratio is only checked once
+// the decompressed body exceeds the minimum size set by
+// WithDecompressRatioMinBytes. This can catch "zip bombs" that would otherwise
+// slip under the absolute size limit. By default, the ratio is not checked.
+func WithMaxDecompressRatio(ratio float64) DecompressOption {
+	return func(config *decompressConfig) {
+		config.maxRatio = ratio
+	}
+}
+
+// WithDecompressRatioMinBytes sets the number of decompressed bytes that must
+// be read before the ratio set with WithMaxDecompressRatio is enforced.
+// Defaults to 1MiB.
+func WithDecompressRatioMinBytes(size int64) DecompressOption {
+	return func(config *decompressConfig) {
+		config.ratioMinBytes = size
+	}
+}
+
+// Decompress is a middleware that transparently decompresses request bodies
+// sent with a gzip Content-Encoding.
+//
+// Requests with a body that isn't valid gzip data are rejected with a 400
+// response. If the decompressed body exceeds the configured limits, reading it
+// will return ErrDecompressLimit and the response will be replaced with a 413
+// response.
+func Decompress(opts ...DecompressOption) func(http.Handler) http.Handler {
+	config := &decompressConfig{
+		maxSize:       10 * 1024 * 1024,
+		ratioMinBytes: 1024 * 1024,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if r.Body == nil || (encoding != "gzip" && encoding != "x-gzip") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			compressed := &countingReader{r: r.Body}
+			reader, err := gzip.NewReader(compressed)
+			if err != nil {
+				http.Error(w, "Invalid gzip request body", http.StatusBadRequest)
+				return
+			}
+
+			body := &decompressReader{
+				reader:     reader,
+				closer:     r.Body,
+				compressed: compressed,
+				conf:       config,
+			}
+
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			r.Body = body
+
+			wrapped := &decompressWrapper{
+				ResponseWriter: w,
+				body:           body,
+			}
+			next.ServeHTTP(wrapped, r)
+
+			if !wrapped.headers && body.exceeded {
+				wrapped.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type decompressReader struct {
+	reader     io.Reader
+	closer     io.Closer
+	compressed *countingReader
+	conf       *decompressConfig
+	read       int64
+	exceeded   bool
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.exceeded {
+		return 0, ErrDecompressLimit
+	}
+
+	n, err := d.reader.Read(p)
+	d.read += int64(n)
+
+	if d.conf.maxSize > 0 && d.read > d.conf.maxSize {
+		d.exceeded = true
+	}
+
+	if d.conf.maxRatio > 0 && d.read >= d.conf.ratioMinBytes && d.compressed.n > 0 {
+		if float64(d.read)/float64(d.compressed.n) > d.conf.maxRatio {
+			d.exceeded = true
+		}
+	}
+
+	if d.exceeded {
+		return 0, ErrDecompressLimit
+	}
+	return n, err
+}
+
+func (d *decompressReader) Close() error {
+	return d.closer.Close()
+}
+
+type decompressWrapper struct {
+	http.ResponseWriter
+	body    *decompressReader
+	drop    bool
+	headers bool
+}
+
+func (d *decompressWrapper) WriteHeader(code int) {
+	d.headers = true
+	if d.body.exceeded {
+		d.drop = true
+		http.Error(d.ResponseWriter, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *decompressWrapper) Write(b []byte) (int, error) {
+	if !d.headers {
+		d.WriteHeader(http.StatusOK)
+	}
+
+	if d.drop {
+		return len(b), nil
+	}
+
+	return d.ResponseWriter.Write(b)
+}
+
+func (d *decompressWrapper) Flush() {
+	if flusher, ok := d.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func readBodyHandler(body *[]byte, readErr *error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*body, *readErr = io.ReadAll(r.Body)
+		if *readErr != nil {
+			http.Error(w, "read failed", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestDecompress_GzipBody(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(gzipBytes(t, []byte("hello world"))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, readErr)
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, req.Header.Get("Content-Encoding"))
+}
+
+func TestDecompress_UncompressedBody(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader("hello world"))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, readErr)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestDecompress_InvalidGzip(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress()(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Nil(t, body)
+}
+
+func TestDecompress_MaxSize(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress(WithMaxDecompressedSize(1024))(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(gzipBytes(t, bytes.Repeat([]byte("a"), 4096))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrDecompressLimit)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "read failed")
+}
+
+func TestDecompress_MaxRatio(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress(
+		WithMaxDecompressedSize(0),
+		WithMaxDecompressRatio(10),
+		WithDecompressRatioMinBytes(1024),
+	)(readBodyHandler(&body, &readErr))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(gzipBytes(t, bytes.Repeat([]byte("a"), 1024*1024))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.ErrorIs(t, readErr, ErrDecompressLimit)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestDecompress_RatioBelowMinBytes(t *testing.T) {
+	var body []byte
+	var readErr error
+	handler := Decompress(
+		WithMaxDecompressRatio(10),
+		WithDecompressRatioMinBytes(1024*1024),
+	)(readBodyHandler(&body, &readErr))
+
+	payload := bytes.Repeat([]byte("a"), 4096)
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(gzipBytes(t, payload)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, readErr)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestDecompress_LimitWithoutResponse(t *testing.T) {
+	handler := Decompress(WithMaxDecompressedSize(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(gzipBytes(t, bytes.Repeat([]byte("a"), 4096))))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import "net/http"
+
+type drainingConfig struct {
+	draining   func() bool
+	healthPath string
+}
+
+type DrainingOption func(*drainingConfig)
+
+// WithDrainFlag sets a function that reports whether the server is draining,
+// e.g. because shutdown has begun. By default, the server is never considered
+// to be draining.
+func WithDrainFlag(draining func() bool) DrainingOption {
+	return func(config *drainingConfig) {
+		config.draining = draining
+	}
+}
+
+// WithDrainHealthPath sets the path of the health check endpoint that should
+// fail while draining. Defaults to "/healthz".
+func WithDrainHealthPath(path string) DrainingOption {
+	return func(config *drainingConfig) {
+		config.healthPath = path
+	}
+}
+
+// Draining is a middleware that fails health checks while the server is
+// draining, so that load balancers stop sending it new traffic during a
+// rolling deploy.
+//
+// While the flag set by WithDrainFlag reports that the server is draining,
+// requests for the health check path are responded to with a 503 Service
+// Unavailable response. Chain this middleware with ErrorHandler to customise
+// this. Otherwise, requests for the health check path are passed to the next
+// handler, which should respond to them as normal. All other requests are
+// always passed to the next handler, so that in-flight and new traffic
+// continues to be served while the load balancer drains.
+func Draining(opts ...DrainingOption) func(http.Handler) http.Handler {
+	config := &drainingConfig{
+		draining:   func() bool { return false },
+		healthPath: "/healthz",
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == config.healthPath && config.draining() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
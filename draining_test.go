@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraining(t *testing.T) {
+	draining := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api"))
+	})
+
+	handler := Draining(WithDrainFlag(func() bool { return draining }))(mux)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/healthz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "ok", rr.Body.String())
+
+	draining = true
+
+	rr = serve("/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	rr = serve("/api")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "api", rr.Body.String())
+}
+
+func TestDraining_CustomHealthPath(t *testing.T) {
+	handler := Draining(
+		WithDrainFlag(func() bool { return true }),
+		WithDrainHealthPath("/status"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/status"))
+	assert.Equal(t, http.StatusOK, serve("/healthz"))
+}
+
+func TestDraining_DefaultNeverDraining(t *testing.T) {
+	handler := Draining()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
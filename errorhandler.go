@@ -1,10 +1,21 @@
 package middleware
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type originalResponseHeadersKey struct{}
 
 type errorHandlerConfig struct {
-	handlers     map[int]http.Handler
-	clearHeaders bool
+	handlers           map[int]http.Handler
+	emptyBody          http.Handler
+	clearHeaders       bool
+	preserveHeaders    []string
+	passthroughHeaders bool
+	chain              func(http.Handler) http.Handler
+	statusMapping      map[int]int
 }
 
 type ErrorHandlerOption func(*errorHandlerConfig)
@@ -17,27 +28,103 @@ func WithErrorHandler(statusCode int, handler http.Handler) ErrorHandlerOption {
 	}
 }
 
+// WithStatusMapping sets status codes that should be replaced with other codes
+// when written by the next handler in the chain, e.g. to present an upstream
+// 418 to users as a 400. The mapped code is used both to look up the handler
+// registered with WithErrorHandler and as the status sent to the client if
+// there is no such handler. Mappings are applied once, and are not chained.
+func WithStatusMapping(mapping map[int]int) ErrorHandlerOption {
+	return func(cfg *errorHandlerConfig) {
+		cfg.statusMapping = mapping
+	}
+}
+
+// WithEmptyBodyHandler registers a handler to be invoked when the next handler
+// in the chain completes with a 200 status and an empty body, which usually
+// indicates a misconfiguration. HEAD requests are never treated as empty.
+//
+// When this is set, writing of a 200 status is delayed until the next handler
+// writes some data or flushes the response.
+func WithEmptyBodyHandler(handler http.Handler) ErrorHandlerOption {
+	return func(cfg *errorHandlerConfig) {
+		cfg.emptyBody = handler
+	}
+}
+
 // WithClearHeadersOnError sets whether or not the headers should be cleared
-// when a custom handler is invoked. True by default.
+// when a custom handler is invoked. Headers configured with
+// WithPreservedHeaders are kept regardless. True by default.
 func WithClearHeadersOnError(clearHeaders bool) ErrorHandlerOption {
 	return func(cfg *errorHandlerConfig) {
 		cfg.clearHeaders = clearHeaders
 	}
 }
 
+// WithPreservedHeaders sets the headers that are kept when headers are cleared
+// before a custom handler is invoked, replacing the default set. By default,
+// only Set-Cookie is preserved, so that (for example) refreshed session cookies
+// aren't lost when an error occurs. Call with no arguments to clear all
+// headers.
+func WithPreservedHeaders(names ...string) ErrorHandlerOption {
+	return func(cfg *errorHandlerConfig) {
+		cfg.preserveHeaders = names
+	}
+}
+
+// WithErrorHandlerPassthroughHeaders sets whether a copy of the headers set by
+// the next handler should be made available to custom handlers, via
+// OriginalResponseHeaders. This allows custom handlers to selectively re-apply
+// headers (such as Retry-After) even when the headers are cleared. False by
+// default.
+func WithErrorHandlerPassthroughHeaders(passthrough bool) ErrorHandlerOption {
+	return func(cfg *errorHandlerConfig) {
+		cfg.passthroughHeaders = passthrough
+	}
+}
+
+// WithErrorHandlerChain wraps all custom handlers (including the one set by
+// WithEmptyBodyHandler) in the given middleware, so that error responses can
+// receive the same treatment as normal responses even when ErrorHandler is
+// outside of other middleware. Use Chain to apply multiple middleware.
+func WithErrorHandlerChain(chain func(http.Handler) http.Handler) ErrorHandlerOption {
+	return func(cfg *errorHandlerConfig) {
+		cfg.chain = chain
+	}
+}
+
+// OriginalResponseHeaders returns the headers that were set by the handler
+// whose response was replaced by ErrorHandler. Returns nil if the
+// WithErrorHandlerPassthroughHeaders option was not enabled.
+func OriginalResponseHeaders(r *http.Request) http.Header {
+	if h, ok := r.Context().Value(originalResponseHeadersKey{}).(http.Header); ok {
+		return h
+	}
+	return nil
+}
+
 // ErrorHandler is a middleware that handles HTTP status codes by invoking
 // a custom handler. Specific error codes can be handled by calling
 // WithErrorHandler. If the next handler writes a status code that has a
 // registered handler, its response will be dropped.
 func ErrorHandler(opts ...ErrorHandlerOption) func(http.Handler) http.Handler {
 	config := &errorHandlerConfig{
-		handlers:     make(map[int]http.Handler),
-		clearHeaders: true,
+		handlers:        make(map[int]http.Handler),
+		clearHeaders:    true,
+		preserveHeaders: []string{"Set-Cookie"},
 	}
 	for _, opt := range opts {
 		opt(config)
 	}
 
+	if config.chain != nil {
+		for code, h := range config.handlers {
+			config.handlers[code] = config.chain(h)
+		}
+		if config.emptyBody != nil {
+			config.emptyBody = config.chain(config.emptyBody)
+		}
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			wrapped := &errorHandlingWrapper{
@@ -48,6 +135,7 @@ func ErrorHandler(opts ...ErrorHandlerOption) func(http.Handler) http.Handler {
 			}
 
 			next.ServeHTTP(wrapped, r)
+			wrapped.finish()
 		})
 	}
 }
@@ -58,20 +146,27 @@ type errorHandlingWrapper struct {
 	conf    *errorHandlerConfig
 	drop    bool
 	headers bool
+	pending bool
 }
 
 func (e *errorHandlingWrapper) WriteHeader(code int) {
-	e.headers = true
-	if h, ok := e.conf.handlers[code]; ok {
-		e.drop = true
-
-		if e.conf.clearHeaders {
-			for k := range e.ResponseWriter.Header() {
-				e.ResponseWriter.Header().Del(k)
-			}
+	if isInformational(code) {
+		if !e.drop {
+			e.ResponseWriter.WriteHeader(code)
 		}
+		return
+	}
+
+	e.headers = true
+	if mapped, ok := e.conf.statusMapping[code]; ok {
+		code = mapped
+	}
 
-		h.ServeHTTP(e.ResponseWriter, e.req)
+	if h, ok := e.conf.handlers[code]; ok {
+		e.serveInstead(h)
+	} else if code == http.StatusOK && e.checkEmpty() {
+		// Wait until we know whether there's a body
+		e.pending = true
 	} else {
 		e.ResponseWriter.WriteHeader(code)
 	}
@@ -86,10 +181,76 @@ func (e *errorHandlingWrapper) Write(b []byte) (int, error) {
 		return len(b), nil
 	}
 
+	if e.pending {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		e.commit()
+	}
+
 	return e.ResponseWriter.Write(b)
 }
 
+// checkEmpty determines whether empty responses to this request should be
+// handled by the empty body handler.
+func (e *errorHandlingWrapper) checkEmpty() bool {
+	return e.conf.emptyBody != nil && e.req.Method != http.MethodHead
+}
+
+// commit writes a pending 200 status to the underlying writer.
+func (e *errorHandlingWrapper) commit() {
+	e.pending = false
+	e.ResponseWriter.WriteHeader(http.StatusOK)
+}
+
+// finish is called after the next handler has completed, and invokes the empty
+// body handler if nothing was written.
+func (e *errorHandlingWrapper) finish() {
+	if e.pending || (!e.headers && e.checkEmpty()) {
+		e.pending = false
+		e.headers = true
+		e.serveInstead(e.conf.emptyBody)
+	}
+}
+
+// serveInstead drops the response from the next handler and serves the given
+// handler in its place.
+func (e *errorHandlingWrapper) serveInstead(h http.Handler) {
+	e.drop = true
+
+	req := e.req
+	if e.conf.passthroughHeaders {
+		original := e.ResponseWriter.Header().Clone()
+		req = req.WithContext(context.WithValue(req.Context(), originalResponseHeadersKey{}, original))
+	}
+
+	if e.conf.clearHeaders {
+		header := e.ResponseWriter.Header()
+		for k := range header {
+			if !e.preserved(k) {
+				header.Del(k)
+			}
+		}
+	}
+
+	h.ServeHTTP(e.ResponseWriter, req)
+}
+
+// preserved determines whether the given header should be kept when clearing
+// headers.
+func (e *errorHandlingWrapper) preserved(name string) bool {
+	for i := range e.conf.preserveHeaders {
+		if strings.EqualFold(e.conf.preserveHeaders[i], name) {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *errorHandlingWrapper) Flush() {
+	if e.pending {
+		e.commit()
+	}
 	if flusher, ok := e.ResponseWriter.(http.Flusher); ok {
 		flusher.Flush()
 	}
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"compress/gzip"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestErrorHandler_NoErrorHandlerRegistered(t *testing.T) {
@@ -174,3 +177,319 @@ func TestErrorHandler_HeadersNotClearedWhenDisabled(t *testing.T) {
 	assert.Equal(t, "original-value", rr.Header().Get("X-Original-Header"))
 	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 }
+
+func TestErrorHandler_EmptyBodyHandler(t *testing.T) {
+	emptyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("nothing here"))
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		handler        http.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:   "explicit 200 with no body",
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "nothing here",
+		},
+		{
+			name:           "nothing written",
+			method:         "GET",
+			handler:        func(w http.ResponseWriter, r *http.Request) {},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "nothing here",
+		},
+		{
+			name:   "empty writes",
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte{})
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "nothing here",
+		},
+		{
+			name:   "200 with body",
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("content"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "content",
+		},
+		{
+			name:   "implicit 200 with body",
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("content"))
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "content",
+		},
+		{
+			name:   "other status with no body",
+			method: "GET",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedBody:   "",
+		},
+		{
+			name:   "head request",
+			method: "HEAD",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ErrorHandler(WithEmptyBodyHandler(emptyHandler))(tt.handler)
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestErrorHandler_EmptyBodyHandlerAfterFlush(t *testing.T) {
+	handler := ErrorHandler(WithEmptyBodyHandler(http.NotFoundHandler()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, rr.Flushed)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestErrorHandler_PassthroughHeaders(t *testing.T) {
+	unavailableHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retry := OriginalResponseHeaders(r).Get("Retry-After"); retry != "" {
+			w.Header().Set("Retry-After", retry)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("try again later"))
+	})
+
+	handler := ErrorHandler(
+		WithErrorHandler(http.StatusServiceUnavailable, unavailableHandler),
+		WithErrorHandlerPassthroughHeaders(true),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.Header().Set("X-Internal", "secret")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "try again later", rr.Body.String())
+	assert.Equal(t, "120", rr.Header().Get("Retry-After"))
+	assert.Empty(t, rr.Header().Get("X-Internal"))
+}
+
+func TestErrorHandler_PassthroughHeadersDisabled(t *testing.T) {
+	var original http.Header
+	handler := ErrorHandler(
+		WithErrorHandler(http.StatusServiceUnavailable, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			original = OriginalResponseHeaders(r)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.Nil(t, original)
+}
+
+func TestErrorHandler_Informational(t *testing.T) {
+	handler := ErrorHandler(
+		WithErrorHandler(http.StatusEarlyHints, http.NotFoundHandler()),
+		WithEmptyBodyHandler(http.NotFoundHandler()),
+	)(earlyHintsHandler)
+
+	informational, res, body := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "test content", body)
+}
+
+func TestErrorHandler_WithErrorHandlerChain(t *testing.T) {
+	notFoundHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found page"))
+	})
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("original"))
+	})
+
+	handler := ErrorHandler(
+		WithErrorHandler(http.StatusNotFound, notFoundHandler),
+		WithErrorHandlerChain(Chain(WithMiddleware(
+			Compress(),
+			Headers(WithHeader("X-Frame-Options", "DENY")),
+		))),
+	)(nextHandler)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "DENY", rr.Header().Get("X-Frame-Options"))
+
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "custom not found page", string(body))
+}
+
+func TestErrorHandler_WithErrorHandlerChainNotAppliedToNormalResponses(t *testing.T) {
+	handler := ErrorHandler(
+		WithErrorHandler(http.StatusNotFound, http.NotFoundHandler()),
+		WithErrorHandlerChain(Headers(WithHeader("X-Error-Page", "true"))),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("X-Error-Page"))
+	assert.Equal(t, "ok", rr.Body.String())
+}
+
+func TestErrorHandler_PreservedHeaders(t *testing.T) {
+	errorHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("custom 500"))
+	})
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Header().Set("X-Original-Header", "original-value")
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name      string
+		opts      []ErrorHandlerOption
+		cookie    string
+		requestID string
+	}{
+		{
+			name:   "default",
+			cookie: "session=abc123",
+		},
+		{
+			name:      "custom",
+			opts:      []ErrorHandlerOption{WithPreservedHeaders("x-request-id")},
+			requestID: "req-1",
+		},
+		{
+			name: "none",
+			opts: []ErrorHandlerOption{WithPreservedHeaders()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]ErrorHandlerOption{WithErrorHandler(http.StatusInternalServerError, errorHandler)}, tt.opts...)
+			handler := ErrorHandler(opts...)(nextHandler)
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusInternalServerError, rr.Code)
+			assert.Equal(t, "custom 500", rr.Body.String())
+			assert.Equal(t, tt.cookie, rr.Header().Get("Set-Cookie"))
+			assert.Equal(t, tt.requestID, rr.Header().Get("X-Request-Id"))
+			assert.Empty(t, rr.Header().Get("X-Original-Header"))
+		})
+	}
+}
+
+func TestErrorHandler_StatusMapping(t *testing.T) {
+	badRequestHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("custom 400"))
+	})
+
+	tests := []struct {
+		name           string
+		status         int
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"mapped to handled status", http.StatusTeapot, http.StatusBadRequest, "custom 400"},
+		{"mapped to unhandled status", http.StatusConflict, http.StatusGone, "original"},
+		{"unmapped", http.StatusNotFound, http.StatusNotFound, "original"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ErrorHandler(
+				WithErrorHandler(http.StatusBadRequest, badRequestHandler),
+				WithStatusMapping(map[int]int{
+					http.StatusTeapot:   http.StatusBadRequest,
+					http.StatusConflict: http.StatusGone,
+				}),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte("original"))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import "net/http"
+
+// FlushOnComplete is a middleware that flushes the response once the next
+// handler has returned, if the http.ResponseWriter supports flushing. This
+// ensures any data buffered by other middleware is sent even if the handler
+// doesn't flush it itself.
+func FlushOnComplete() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bufferingWriter struct {
+	http.ResponseWriter
+	buffer  bytes.Buffer
+	flushes int
+}
+
+func (b *bufferingWriter) Write(p []byte) (int, error) {
+	return b.buffer.Write(p)
+}
+
+func (b *bufferingWriter) Flush() {
+	b.flushes++
+	b.ResponseWriter.Write(b.buffer.Bytes())
+	b.buffer.Reset()
+}
+
+func TestFlushOnComplete(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writer := &bufferingWriter{ResponseWriter: rr}
+
+	handler := FlushOnComplete()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("line one\n"))
+		w.Write([]byte("line two\n"))
+		assert.Empty(t, rr.Body.String())
+	}))
+
+	handler.ServeHTTP(writer, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, 1, writer.flushes)
+	assert.Equal(t, "line one\nline two\n", rr.Body.String())
+}
+
+func TestFlushOnComplete_NotFlusher(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	handler := FlushOnComplete()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	// Hide the recorder's Flush method
+	handler.ServeHTTP(struct{ http.ResponseWriter }{rr}, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, "test content", rr.Body.String())
+	assert.False(t, rr.Flushed)
+}
@@ -3,7 +3,8 @@ package middleware
 import "net/http"
 
 type headersConfig struct {
-	headers map[string][]string
+	headers   map[string][]string
+	overrides map[string][]string
 }
 
 type HeadersOption func(*headersConfig)
@@ -16,12 +17,23 @@ func WithHeader(key, value string) HeadersOption {
 	}
 }
 
+// WithHeaderOverride specifies one header to be set on a response, replacing
+// any values for the same key set by the next handler. Unlike WithHeader, this
+// makes the middleware authoritative for the header. The same key can be used
+// multiple times, resulting in multiple headers being set.
+func WithHeaderOverride(key, value string) HeadersOption {
+	return func(config *headersConfig) {
+		config.overrides[key] = append(config.overrides[key], value)
+	}
+}
+
 // Headers is a middleware that adds headers to a response as late as possible.
 // This may be useful when chained with other middleware such as ErrorHandler
 // that change headers.
 func Headers(opts ...HeadersOption) func(http.Handler) http.Handler {
 	conf := &headersConfig{
-		headers: make(map[string][]string),
+		headers:   make(map[string][]string),
+		overrides: make(map[string][]string),
 	}
 	for _, opt := range opts {
 		opt(conf)
@@ -29,10 +41,16 @@ func Headers(opts ...HeadersOption) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			next.ServeHTTP(&headersWrapper{
+			wrapped := &headersWrapper{
 				ResponseWriter: w,
 				conf:           conf,
-			}, r)
+			}
+			next.ServeHTTP(wrapped, r)
+
+			if !wrapped.headers {
+				// The server will write the headers once we return
+				wrapped.addHeaders()
+			}
 		})
 	}
 }
@@ -44,13 +62,29 @@ type headersWrapper struct {
 }
 
 func (h *headersWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		h.ResponseWriter.WriteHeader(code)
+		return
+	}
+
 	h.headers = true
+	h.addHeaders()
+	h.ResponseWriter.WriteHeader(code)
+}
+
+// addHeaders adds the configured headers to the response.
+func (h *headersWrapper) addHeaders() {
+	for k := range h.conf.overrides {
+		h.ResponseWriter.Header().Del(k)
+		for _, v := range h.conf.overrides[k] {
+			h.ResponseWriter.Header().Add(k, v)
+		}
+	}
 	for k := range h.conf.headers {
 		for _, v := range h.conf.headers[k] {
 			h.ResponseWriter.Header().Add(k, v)
 		}
 	}
-	h.ResponseWriter.WriteHeader(code)
 }
 
 func (h *headersWrapper) Write(b []byte) (int, error) {
@@ -65,3 +99,46 @@ func (h *headersWrapper) Flush() {
 		flusher.Flush()
 	}
 }
+
+// isInformational determines whether the status code is for an informational
+// (1xx) response, which may be sent any number of times before the final
+// response. As with net/http, 101 Switching Protocols is treated as final.
+func isInformational(code int) bool {
+	return code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols
+}
+
+// statusRecorder is a response writer that records the final status code and
+// the number of body bytes written, for middleware that act on the outcome of
+// a request. A status of 0 means nothing has been written yet.
+type statusRecorder struct {
+	http.ResponseWriter
+	written int
+	status  int
+	headers bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if isInformational(code) {
+		s.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	s.headers = true
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if !s.headers {
+		s.WriteHeader(http.StatusOK)
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.written += n
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
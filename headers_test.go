@@ -1,11 +1,16 @@
 package middleware
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHeaders_SingleHeader(t *testing.T) {
@@ -93,3 +98,106 @@ func TestHeaders_WriteWithoutWriteHeader(t *testing.T) {
 	assert.Equal(t, "test-value", rr.Header().Get("X-Custom"))
 	assert.Equal(t, "test content", rr.Body.String())
 }
+
+func TestHeaders_NoWrites(t *testing.T) {
+	handler := Headers(WithHeader("X-Custom", "test-value"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "test-value", rr.Header().Get("X-Custom"))
+}
+
+func TestHeaders_Override(t *testing.T) {
+	handler := Headers(
+		WithHeader("X-Append", "middleware-value"),
+		WithHeaderOverride("X-Override", "middleware-value"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Append", "handler-value")
+		w.Header().Add("X-Override", "handler-value1")
+		w.Header().Add("X-Override", "handler-value2")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{"handler-value", "middleware-value"}, rr.Header().Values("X-Append"))
+	assert.Equal(t, []string{"middleware-value"}, rr.Header().Values("X-Override"))
+}
+
+func TestHeaders_OverrideMultipleValues(t *testing.T) {
+	handler := Headers(
+		WithHeaderOverride("X-Override", "value1"),
+		WithHeaderOverride("X-Override", "value2"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{"value1", "value2"}, rr.Header().Values("X-Override"))
+}
+
+// earlyHintsHandler sends a 103 Early Hints response with a Link header, and
+// then a 200 response.
+var earlyHintsHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Link", "</style.css>; rel=preload")
+	w.WriteHeader(http.StatusEarlyHints)
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("test content"))
+})
+
+// serveWithInformational serves a request to the handler using a real server,
+// as httptest.ResponseRecorder doesn't support informational responses. It
+// returns the headers of each informational response received, keyed by
+// status code, along with the final response and its body.
+func serveWithInformational(t *testing.T, handler http.Handler, requestHeaders http.Header) (map[int]textproto.MIMEHeader, *http.Response, string) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	informational := make(map[int]textproto.MIMEHeader)
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			informational[code] = header
+			return nil
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	for k, v := range requestHeaders {
+		req.Header[k] = v
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	return informational, res, string(body)
+}
+
+func TestHeaders_Informational(t *testing.T) {
+	handler := Headers(WithHeader("X-Custom", "value"))(earlyHintsHandler)
+
+	informational, res, body := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, "</style.css>; rel=preload", informational[http.StatusEarlyHints].Get("Link"))
+	assert.Empty(t, informational[http.StatusEarlyHints].Get("X-Custom"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "value", res.Header.Get("X-Custom"))
+	assert.Equal(t, "test content", body)
+}
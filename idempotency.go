@@ -0,0 +1,300 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdempotentResponse is a response that has been recorded by the Idempotency
+// middleware, so that it can be replayed.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyStore stores responses recorded by the Idempotency middleware.
+// Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Get returns the response previously stored for the given key, if any.
+	Get(key string) (*IdempotentResponse, bool)
+	// Set stores a response for the given key.
+	Set(key string, response *IdempotentResponse)
+}
+
+type idempotencyConfig struct {
+	headerName string
+	scope      func(*http.Request) string
+}
+
+type IdempotencyOption func(*idempotencyConfig)
+
+// WithIdempotencyHeader sets the name of the header that the Idempotency
+// middleware reads keys from. Defaults to "Idempotency-Key".
+func WithIdempotencyHeader(headerName string) IdempotencyOption {
+	return func(config *idempotencyConfig) {
+		config.headerName = headerName
+	}
+}
+
+// WithIdempotencyScope sets a function that identifies the client making a
+// request. Idempotency keys are only shared between requests with the same
+// method, path and scope, so one client can't be served another's response by
+// reusing or guessing its key. Defaults to the request's Authorization and
+// Cookie headers if either is present, or the client's IP address otherwise.
+func WithIdempotencyScope(scope func(*http.Request) string) IdempotencyOption {
+	return func(config *idempotencyConfig) {
+		config.scope = scope
+	}
+}
+
+func defaultIdempotencyScope(r *http.Request) string {
+	authorization := r.Header.Values("Authorization")
+	cookies := r.Header.Values("Cookie")
+	if len(authorization) == 0 && len(cookies) == 0 {
+		return defaultRateLimitKey(r)
+	}
+
+	var b strings.Builder
+	for _, v := range authorization {
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	b.WriteByte(0)
+	for _, v := range cookies {
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// idempotencyStoreKey derives the key used with the store, so that responses
+// are only replayed to the same client making the same request, and so that
+// credentials aren't stored verbatim.
+func idempotencyStoreKey(r *http.Request, scope, key string) string {
+	hash := sha256.New()
+	for _, part := range []string{r.Method, r.URL.Path, scope, key} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotency is a middleware that deduplicates retried requests that share
+// the same idempotency key.
+//
+// The first request with a given key is passed to the next handler, and its
+// response (status, headers and body) is recorded in the store. Subsequent
+// requests with the same key are served the recorded response without
+// invoking the next handler. If a duplicate request arrives while the first
+// is still being handled, it waits for the first to complete.
+//
+// Server errors (a status of 500 or above) are not recorded, nor is anything
+// if the next handler panics, so that retries are passed to the next handler
+// again.
+//
+// Keys are scoped to the request method, path and client (see
+// WithIdempotencyScope), and the store is given a hash of all of them rather
+// than the raw key.
+//
+// GET, HEAD and OPTIONS requests, and requests without a key, are always
+// passed through to the next handler.
+func Idempotency(store IdempotencyStore, opts ...IdempotencyOption) func(http.Handler) http.Handler {
+	config := &idempotencyConfig{
+		headerName: "Idempotency-Key",
+		scope:      defaultIdempotencyScope,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var mutex sync.Mutex
+	inFlight := make(map[string]chan struct{})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(config.headerName)
+			if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key = idempotencyStoreKey(r, config.scope(r), key)
+
+			// Only the in-flight bookkeeping happens under the lock; the store
+			// may be slow, so it's consulted once this request owns the key.
+			var done chan struct{}
+			for done == nil {
+				mutex.Lock()
+				wait, ok := inFlight[key]
+				if !ok {
+					done = make(chan struct{})
+					inFlight[key] = done
+				}
+				mutex.Unlock()
+
+				if ok {
+					select {
+					case <-wait:
+					case <-r.Context().Done():
+						return
+					}
+				}
+			}
+
+			var response *IdempotentResponse
+			defer func() {
+				if response != nil {
+					// This must happen before the key is released, so the next
+					// owner finds the response in the store.
+					store.Set(key, response)
+				}
+				mutex.Lock()
+				delete(inFlight, key)
+				mutex.Unlock()
+				close(done)
+			}()
+
+			if res, ok := store.Get(key); ok {
+				replayIdempotentResponse(w, res)
+				return
+			}
+
+			wrapped := &idempotencyWrapper{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+			}
+
+			next.ServeHTTP(wrapped, r)
+			if wrapped.status < http.StatusInternalServerError {
+				response = wrapped.response()
+			}
+		})
+	}
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, res *IdempotentResponse) {
+	for k, v := range res.Header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(res.StatusCode)
+	w.Write(res.Body)
+}
+
+type idempotencyWrapper struct {
+	http.ResponseWriter
+	status  int
+	header  http.Header
+	body    bytes.Buffer
+	headers bool
+}
+
+func (i *idempotencyWrapper) WriteHeader(code int) {
+	i.headers = true
+	i.status = code
+	i.header = i.ResponseWriter.Header().Clone()
+	i.ResponseWriter.WriteHeader(code)
+}
+
+func (i *idempotencyWrapper) Write(b []byte) (int, error) {
+	if !i.headers {
+		i.WriteHeader(http.StatusOK)
+	}
+	i.body.Write(b)
+	return i.ResponseWriter.Write(b)
+}
+
+func (i *idempotencyWrapper) response() *IdempotentResponse {
+	header := i.header
+	if !i.headers {
+		header = i.ResponseWriter.Header().Clone()
+	}
+
+	return &IdempotentResponse{
+		StatusCode: i.status,
+		Header:     header,
+		Body:       i.body.Bytes(),
+	}
+}
+
+func (i *idempotencyWrapper) Flush() {
+	if flusher, ok := i.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore that expires
+// responses after a fixed TTL.
+type MemoryIdempotencyStore struct {
+	ttl       time.Duration
+	clock     func() time.Time
+	mutex     sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	response *IdempotentResponse
+	expires  time.Time
+}
+
+// NewMemoryIdempotencyStore creates a new in-memory IdempotencyStore that
+// keeps responses for the given TTL.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		clock:   time.Now,
+		entries: make(map[string]memoryIdempotencyEntry),
+	}
+}
+
+// Get returns the response stored for the given key, if it has not expired.
+func (m *MemoryIdempotencyStore) Get(key string) (*IdempotentResponse, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !m.clock().Before(entry.expires) {
+		delete(m.entries, key)
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+// Set stores the response for the given key. Expired entries are removed from
+// the store at the same time, at most once per TTL.
+func (m *MemoryIdempotencyStore) Set(key string, response *IdempotentResponse) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.clock()
+	m.sweep(now)
+
+	m.entries[key] = memoryIdempotencyEntry{
+		response: response,
+		expires:  now.Add(m.ttl),
+	}
+}
+
+// sweep removes any expired entries, at most once per TTL.
+func (m *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+
+	for k := range m.entries {
+		if !now.Before(m.entries[k].expires) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency_FirstRequest(t *testing.T) {
+	store := NewMemoryIdempotencyStore(time.Hour)
+	handler := Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Custom", "value")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	req := httptest.NewRequest("POST", "/test", nil)
+	req.Header.Set("Idempotency-Key", "abc")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "created", rr.Body.String())
+
+	stored, ok := store.Get(idempotencyStoreKey(req, defaultIdempotencyScope(req), "abc"))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusCreated, stored.StatusCode)
+	assert.Equal(t, "value", stored.Header.Get("X-Custom"))
+	assert.Equal(t, "created", string(stored.Body))
+}
+
+func TestIdempotency_DuplicateReplayed(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Custom", "value")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, "value", rr.Header().Get("X-Custom"))
+		assert.Equal(t, "created", rr.Body.String())
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotency_ServerErrorRetried(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func() int {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, http.StatusCreated, serve())
+	assert.Equal(t, http.StatusCreated, serve())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_PanicRetried(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("oops")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func() int {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Panics(t, func() { serve() })
+	assert.Equal(t, http.StatusCreated, serve())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_DifferentKeys(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	for _, key := range []string{"abc", "def", ""} {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Idempotency-Key", key)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotency_ScopedToRequestAndClient(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Set-Cookie", "session="+r.Header.Get("Authorization"))
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("Authorization")))
+	}))
+
+	requests := []struct {
+		method, path, authorization, remoteAddr string
+	}{
+		{"POST", "/test", "Bearer alice", "192.0.2.1:1234"},
+		{"POST", "/test", "Bearer bob", "192.0.2.1:1234"},
+		{"POST", "/other", "Bearer alice", "192.0.2.1:1234"},
+		{"PUT", "/test", "Bearer alice", "192.0.2.1:1234"},
+		{"POST", "/test", "", "192.0.2.1:1234"},
+		{"POST", "/test", "", "192.0.2.2:1234"},
+	}
+
+	for _, tt := range requests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("Idempotency-Key", "abc")
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, tt.method+" "+tt.path+" "+tt.authorization, rr.Body.String())
+		assert.Equal(t, "session="+tt.authorization, rr.Header().Get("Set-Cookie"))
+	}
+
+	assert.Equal(t, len(requests), calls)
+}
+
+func TestIdempotency_CustomScope(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour), WithIdempotencyScope(func(r *http.Request) string {
+		return r.Header.Get("X-Tenant")
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	for _, tenant := range []string{"a", "a", "b"} {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("Idempotency-Key", "abc")
+		req.Header.Set("X-Tenant", tenant)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2, calls)
+}
+
+type blockingIdempotencyStore struct {
+	*MemoryIdempotencyStore
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingIdempotencyStore) Get(key string) (*IdempotentResponse, bool) {
+	select {
+	case b.started <- struct{}{}:
+		<-b.release
+	default:
+	}
+	return b.MemoryIdempotencyStore.Get(key)
+}
+
+func TestIdempotency_StoreNotCalledUnderLock(t *testing.T) {
+	store := &blockingIdempotencyStore{
+		MemoryIdempotencyStore: NewMemoryIdempotencyStore(time.Hour),
+		started:                make(chan struct{}),
+		release:                make(chan struct{}),
+	}
+	handler := Idempotency(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/test", nil)
+		req.Header.Set("Idempotency-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		serve("slow")
+	}()
+	<-store.started
+
+	// A request with a different key must not wait for the slow store lookup
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		serve("fast")
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("request blocked behind another key's store lookup")
+	}
+
+	close(store.release)
+	<-blocked
+	<-finished
+}
+
+func TestIdempotency_SafeMethodsIgnored(t *testing.T) {
+	calls := 0
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotency_ConcurrentDuplicates(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	handler := Idempotency(NewMemoryIdempotencyStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Write([]byte("done"))
+	}))
+
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, 5)
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rr *httptest.ResponseRecorder) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", "/test", nil)
+			req.Header.Set("Idempotency-Key", "abc")
+			handler.ServeHTTP(rr, req)
+		}(recorders[i])
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, rr := range recorders {
+		assert.Equal(t, "done", rr.Body.String())
+	}
+}
+
+func TestMemoryIdempotencyStore_Expiry(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.clock = func() time.Time { return now }
+
+	store.Set("abc", &IdempotentResponse{StatusCode: http.StatusOK})
+
+	_, ok := store.Get("abc")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = store.Get("abc")
+	assert.False(t, ok)
+}
+
+func TestMemoryIdempotencyStore_Sweep(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.clock = func() time.Time { return now }
+
+	set := func(offset time.Duration, key string) {
+		now = start.Add(offset)
+		store.Set(key, &IdempotentResponse{StatusCode: http.StatusOK})
+	}
+
+	set(0, "a")
+	set(30*time.Second, "b")
+	set(65*time.Second, "c")
+	assert.Len(t, store.entries, 2, "a should be swept")
+
+	// b has expired, but the last sweep was too recent to sweep again
+	set(100*time.Second, "d")
+	assert.Len(t, store.entries, 3)
+
+	set(130*time.Second, "e")
+	assert.Len(t, store.entries, 2, "b and c should be swept")
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type negotiatedLanguageKey struct{}
+
+// NegotiateLanguage is a middleware that selects the best language to respond
+// with from the supported languages (e.g. "en", "en-GB", "fr"), based on the
+// request's Accept-Language header. The selected language can be retrieved by
+// handlers using NegotiatedLanguage, and is set in the response's
+// Content-Language header (which handlers may override).
+//
+// Language ranges match supported languages that they are a prefix of (so "en"
+// matches "en-GB"), and fall back to supported languages that are a prefix of
+// them (so "en-US" matches "en"). Exact matches are preferred over these.
+// Each supported language takes the q-value of the most specific range that
+// matches it, so "fr;q=0, *" will never select "fr".
+//
+// If none of the supported languages are acceptable, or the request has no
+// Accept-Language header, the first supported language is used.
+func NegotiateLanguage(supported ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			selected := selectLanguage(parseMediaRanges(r.Header.Values("Accept-Language")), supported)
+			if selected == "" && len(supported) > 0 {
+				selected = supported[0]
+			}
+
+			if selected != "" {
+				w.Header().Set("Content-Language", selected)
+			}
+			addVary(w.Header(), "Accept-Language")
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), negotiatedLanguageKey{}, selected)))
+		})
+	}
+}
+
+// NegotiatedLanguage returns the language selected by the NegotiateLanguage
+// middleware, or an empty string if the middleware was not used.
+func NegotiatedLanguage(r *http.Request) string {
+	if l, ok := r.Context().Value(negotiatedLanguageKey{}).(string); ok {
+		return l
+	}
+	return ""
+}
+
+func selectLanguage(ranges map[string]float64, supported []string) string {
+	best := ""
+	bestValue := 0.0
+	bestExact := false
+	for _, language := range supported {
+		value, exact := languageValue(ranges, strings.ToLower(language))
+		if value > bestValue || (value == bestValue && value > 0 && exact && !bestExact) {
+			best = language
+			bestValue = value
+			bestExact = exact
+		}
+	}
+	return best
+}
+
+// languageValue returns the q-value of the most specific range matching the
+// given tag, so that a more specific range with q=0 refuses a language that
+// a less specific one (or "*") would otherwise accept. From most to least
+// specific, ranges match exactly, are a prefix of the tag (longest first),
+// have the tag as a prefix, or are "*".
+func languageValue(ranges map[string]float64, tag string) (value float64, exact bool) {
+	if value, ok := ranges[tag]; ok {
+		return value, true
+	}
+
+	specificity := -1
+	for languageRange, v := range ranges {
+		s := -1
+		switch {
+		case strings.HasPrefix(tag, languageRange+"-"):
+			s = 2 + len(languageRange)
+		case strings.HasPrefix(languageRange, tag+"-"):
+			s = 1
+		case languageRange == "*":
+			s = 0
+		}
+
+		if s > specificity || (s == specificity && v > value) {
+			specificity = s
+			value = v
+		}
+	}
+	return value, false
+}
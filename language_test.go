@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		supported      []string
+		acceptLanguage string
+		expected       string
+	}{
+		{"exact match", []string{"en", "fr", "de"}, "fr", "fr"},
+		{"case insensitive", []string{"en-GB", "fr"}, "EN-gb", "en-GB"},
+		{"q-values", []string{"en", "fr", "de"}, "fr;q=0.5, de;q=0.8", "de"},
+		{"fallback to base language", []string{"en", "fr"}, "en-US", "en"},
+		{"range matches more specific", []string{"en-GB", "fr"}, "en", "en-GB"},
+		{"exact preferred over fallback", []string{"en", "en-US"}, "en-US", "en-US"},
+		{"higher q preferred over exact", []string{"fr", "en"}, "fr-CA;q=0.9, en;q=0.5", "fr"},
+		{"wildcard", []string{"en", "fr"}, "de, *;q=0.5", "en"},
+		{"refused", []string{"en", "fr"}, "en;q=0, fr;q=0.1", "fr"},
+		{"refused despite wildcard", []string{"fr", "en"}, "fr;q=0, *", "en"},
+		{"refused despite base language", []string{"en-US", "en-GB"}, "en-US;q=0, en", "en-GB"},
+		{"most specific range used", []string{"en-GB", "fr"}, "en;q=0.9, en-GB;q=0.1, fr;q=0.5", "fr"},
+		{"no match", []string{"en", "fr"}, "de, ja", "en"},
+		{"no header", []string{"en", "fr"}, "", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var negotiated string
+			handler := NegotiateLanguage(tt.supported...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				negotiated = NegotiatedLanguage(r)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, negotiated)
+			assert.Equal(t, tt.expected, rr.Header().Get("Content-Language"))
+			assert.Equal(t, "Accept-Language", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestNegotiateLanguage_HandlerOverride(t *testing.T) {
+	handler := NegotiateLanguage("en", "fr")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", "de")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Language", "fr")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "de", rr.Header().Get("Content-Language"))
+}
+
+func TestNegotiatedLanguage_NotUsed(t *testing.T) {
+	assert.Empty(t, NegotiatedLanguage(httptest.NewRequest("GET", "/test", nil)))
+}
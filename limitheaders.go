@@ -0,0 +1,61 @@
+package middleware
+
+import "net/http"
+
+type limitHeadersConfig struct {
+	maxCount int
+	maxBytes int
+}
+
+type LimitHeadersOption func(*limitHeadersConfig)
+
+// WithMaxHeaderCount sets the maximum number of header fields a request may
+// contain. Each value of a repeated header counts separately. A value of 0
+// (the default) disables the limit.
+func WithMaxHeaderCount(count int) LimitHeadersOption {
+	return func(config *limitHeadersConfig) {
+		config.maxCount = count
+	}
+}
+
+// WithMaxHeaderBytes sets the maximum combined size of all header names and
+// values in a request. A value of 0 (the default) disables the limit.
+func WithMaxHeaderBytes(bytes int) LimitHeadersOption {
+	return func(config *limitHeadersConfig) {
+		config.maxBytes = bytes
+	}
+}
+
+// LimitHeaders is a middleware that rejects requests with too many headers, or
+// headers that are too large, with a 431 Request Header Fields Too Large
+// response.
+//
+// No limits are applied by default, as http.Server already limits the size of
+// request headers using its MaxHeaderBytes field. Use WithMaxHeaderCount and
+// WithMaxHeaderBytes to configure stricter limits.
+func LimitHeaders(opts ...LimitHeadersOption) func(http.Handler) http.Handler {
+	config := &limitHeadersConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count := 0
+			size := 0
+			for k, values := range r.Header {
+				for _, v := range values {
+					count++
+					size += len(k) + len(v)
+				}
+			}
+
+			if (config.maxCount > 0 && count > config.maxCount) || (config.maxBytes > 0 && size > config.maxBytes) {
+				http.Error(w, http.StatusText(http.StatusRequestHeaderFieldsTooLarge), http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitHeaders_Count(t *testing.T) {
+	tests := []struct {
+		name           string
+		headers        int
+		expectedStatus int
+	}{
+		{"under limit", 5, http.StatusOK},
+		{"at limit", 10, http.StatusOK},
+		{"over limit", 11, http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := LimitHeaders(WithMaxHeaderCount(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			for i := 0; i < tt.headers; i++ {
+				req.Header.Add("X-Header", fmt.Sprintf("%d", i))
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestLimitHeaders_Bytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		valueLength    int
+		expectedStatus int
+	}{
+		{"under limit", 10, http.StatusOK},
+		{"at limit", 93, http.StatusOK},
+		{"over limit", 94, http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := LimitHeaders(WithMaxHeaderBytes(100))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header = http.Header{}
+			req.Header.Set("X-Value", strings.Repeat("a", tt.valueLength))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestLimitHeaders_DisabledByDefault(t *testing.T) {
+	handler := LimitHeaders()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	for i := 0; i < 1000; i++ {
+		req.Header.Add(fmt.Sprintf("X-Header-%d", i), strings.Repeat("a", 100))
+	}
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+type limitQueryParamsConfig struct {
+	response http.Handler
+}
+
+type LimitQueryParamsOption func(*limitQueryParamsConfig)
+
+// WithQueryLimitResponse sets the handler used to respond to requests with
+// too many query parameters. By default, a plain 400 Bad Request response is
+// sent.
+func WithQueryLimitResponse(handler http.Handler) LimitQueryParamsOption {
+	return func(config *limitQueryParamsConfig) {
+		config.response = handler
+	}
+}
+
+// LimitQueryParams is a middleware that rejects requests with more than limit
+// query parameters, to protect handlers that iterate over the parameters from
+// requests with thousands of them. Each value of a repeated parameter counts
+// separately.
+//
+// Parameters are counted without parsing the query string, so this check is
+// cheap even for huge queries. It doesn't limit the size of the query string
+// itself: the whole request line is already bounded by http.Server's
+// MaxHeaderBytes field, and a query string within that limit could still
+// contain a few very large parameters.
+func LimitQueryParams(limit int, opts ...LimitQueryParamsOption) func(http.Handler) http.Handler {
+	config := &limitQueryParamsConfig{
+		response: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		}),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if countQueryParams(r.URL.RawQuery) > limit {
+				config.response.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// countQueryParams counts the parameters in a raw query string in the same way
+// as url.ParseQuery, skipping empty segments.
+func countQueryParams(query string) int {
+	count := 0
+	for query != "" {
+		var param string
+		param, query, _ = strings.Cut(query, "&")
+		if param != "" {
+			count++
+		}
+	}
+	return count
+}
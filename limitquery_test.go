@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitQueryParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+	}{
+		{"no query", "", http.StatusOK},
+		{"under limit", "a=1&b=2", http.StatusOK},
+		{"at limit", "a=1&b=2&c=3", http.StatusOK},
+		{"at limit with empty segments", "a=1&&b=2&c=3&", http.StatusOK},
+		{"over limit", "a=1&b=2&c=3&d=4", http.StatusBadRequest},
+		{"over limit with repeated key", "a=1&a=2&a=3&a=4", http.StatusBadRequest},
+		{"far over limit", strings.Repeat("a=1&", 10000), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := LimitQueryParams(3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestLimitQueryParams_CustomResponse(t *testing.T) {
+	handler := LimitQueryParams(1, WithQueryLimitResponse(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestURITooLong)
+		w.Write([]byte("too many parameters"))
+	})))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?a=1&b=2", nil))
+
+	assert.Equal(t, http.StatusRequestURITooLong, rr.Code)
+	assert.Equal(t, "too many parameters", rr.Body.String())
+}
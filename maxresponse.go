@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrMaxResponseSize is returned when writing to a response wrapped by the
+// MaxResponseSize middleware if the response exceeds the configured size.
+var ErrMaxResponseSize = errors.New("middleware: response exceeds maximum size")
+
+type maxResponseSizeConfig struct {
+	exceeded func(*http.Request)
+}
+
+type MaxResponseSizeOption func(*maxResponseSizeConfig)
+
+// WithMaxResponseExceeded sets a function to be called the first time a
+// response exceeds the maximum size, e.g. to log the offending request. The
+// function may panic with http.ErrAbortHandler to abort the response, so the
+// client can tell it has been truncated.
+func WithMaxResponseExceeded(exceeded func(*http.Request)) MaxResponseSizeOption {
+	return func(config *maxResponseSizeConfig) {
+		config.exceeded = exceeded
+	}
+}
+
+// MaxResponseSize limits the size of response bodies to n bytes, to stop a
+// misbehaving handler from streaming an unbounded amount of data.
+//
+// Once the limit is reached, the response is truncated: the bytes up to the
+// limit are sent, and further writes are discarded and return
+// ErrMaxResponseSize. If the handler declared a Content-Length larger than
+// the limit, net/http will close the connection after the truncated body.
+// Otherwise, the truncated response appears complete to the client; use
+// WithMaxResponseExceeded to abort the response instead.
+func MaxResponseSize(n int64, opts ...MaxResponseSizeOption) func(http.Handler) http.Handler {
+	config := &maxResponseSizeConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&maxResponseSizeWrapper{
+				ResponseWriter: w,
+				req:            r,
+				conf:           config,
+				remaining:      n,
+			}, r)
+		})
+	}
+}
+
+type maxResponseSizeWrapper struct {
+	http.ResponseWriter
+	req       *http.Request
+	conf      *maxResponseSizeConfig
+	remaining int64
+	exceeded  bool
+}
+
+func (m *maxResponseSizeWrapper) Write(b []byte) (int, error) {
+	if m.exceeded {
+		return 0, ErrMaxResponseSize
+	}
+
+	if int64(len(b)) <= m.remaining {
+		n, err := m.ResponseWriter.Write(b)
+		m.remaining -= int64(n)
+		return n, err
+	}
+
+	n, err := m.ResponseWriter.Write(b[:m.remaining])
+	m.remaining -= int64(n)
+	m.exceeded = true
+	if m.conf.exceeded != nil {
+		m.conf.exceeded(m.req)
+	}
+	if err == nil {
+		err = ErrMaxResponseSize
+	}
+	return n, err
+}
+
+func (m *maxResponseSizeWrapper) Flush() {
+	if flusher, ok := m.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
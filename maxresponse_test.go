@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxResponseSize_UnderLimit(t *testing.T) {
+	var writeErr error
+	handler := MaxResponseSize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, writeErr = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NoError(t, writeErr)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "hello", rr.Body.String())
+}
+
+func TestMaxResponseSize_ExactlyAtLimit(t *testing.T) {
+	var writeErrs []error
+	handler := MaxResponseSize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range []string{"hello", "world"} {
+			_, err := w.Write([]byte(s))
+			writeErrs = append(writeErrs, err)
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []error{nil, nil}, writeErrs)
+	assert.Equal(t, "helloworld", rr.Body.String())
+}
+
+func TestMaxResponseSize_OverLimit(t *testing.T) {
+	var written []int
+	var writeErrs []error
+	handler := MaxResponseSize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		for _, s := range []string{"hello", "world", "again"} {
+			n, err := w.Write([]byte(s))
+			written = append(written, n)
+			writeErrs = append(writeErrs, err)
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "hellowor", rr.Body.String())
+	assert.Equal(t, []int{5, 3, 0}, written)
+	assert.NoError(t, writeErrs[0])
+	assert.ErrorIs(t, writeErrs[1], ErrMaxResponseSize)
+	assert.ErrorIs(t, writeErrs[2], ErrMaxResponseSize)
+}
+
+func TestMaxResponseSize_ExceededHook(t *testing.T) {
+	var paths []string
+	handler := MaxResponseSize(4, WithMaxResponseExceeded(func(r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+		w.Write([]byte("world"))
+	}))
+
+	req := httptest.NewRequest("GET", "/big", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "hell", rr.Body.String())
+	assert.Equal(t, []string{"/big"}, paths)
+}
+
+func TestMaxResponseSize_HookNotCalledUnderLimit(t *testing.T) {
+	called := false
+	handler := MaxResponseSize(10, WithMaxResponseExceeded(func(r *http.Request) {
+		called = true
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.False(t, called)
+}
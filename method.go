@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+type normalizeMethodConfig struct {
+	methods       []string
+	rejectUnknown bool
+}
+
+type NormalizeMethodOption func(*normalizeMethodConfig)
+
+// WithKnownMethods adds methods (e.g. "PROPFIND") that NormalizeMethod should
+// treat in the same way as the standard HTTP methods.
+func WithKnownMethods(methods ...string) NormalizeMethodOption {
+	return func(config *normalizeMethodConfig) {
+		config.methods = append(config.methods, methods...)
+	}
+}
+
+// WithRejectUnknownMethods sets whether NormalizeMethod should reject requests
+// that don't use a known method with a 501 Not Implemented response, instead
+// of passing them to the next handler unchanged. Defaults to false.
+func WithRejectUnknownMethods(reject bool) NormalizeMethodOption {
+	return func(config *normalizeMethodConfig) {
+		config.rejectUnknown = reject
+	}
+}
+
+// NormalizeMethod is a middleware that converts the request method to upper
+// case if it matches one of the standard HTTP methods (or those added with
+// WithKnownMethods) case-insensitively. This allows requests from clients that
+// incorrectly send e.g. "get" or "Post" to be routed normally.
+//
+// Methods that aren't known are passed through unchanged, or rejected with a
+// 501 response if WithRejectUnknownMethods is enabled. Chain this middleware
+// with ErrorHandler to customise this.
+func NormalizeMethod(opts ...NormalizeMethodOption) func(http.Handler) http.Handler {
+	config := &normalizeMethodConfig{
+		methods: append([]string(nil), standardMethods...),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	known := make(map[string]string)
+	for _, method := range config.methods {
+		known[strings.ToUpper(method)] = method
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if method, ok := known[strings.ToUpper(r.Method)]; ok {
+				r.Method = method
+			} else if config.rejectUnknown {
+				w.WriteHeader(http.StatusNotImplemented)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMethod(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []NormalizeMethodOption
+		method         string
+		expectedStatus int
+		expectedMethod string
+	}{
+		{"uppercase", nil, "GET", http.StatusOK, "GET"},
+		{"lowercase", nil, "get", http.StatusOK, "GET"},
+		{"mixed case", nil, "Post", http.StatusOK, "POST"},
+		{"lowercase delete", nil, "delete", http.StatusOK, "DELETE"},
+		{"unknown method", nil, "purge", http.StatusOK, "purge"},
+		{"custom method", []NormalizeMethodOption{WithKnownMethods("PROPFIND")}, "propfind", http.StatusOK, "PROPFIND"},
+		{"unknown method rejected", []NormalizeMethodOption{WithRejectUnknownMethods(true)}, "purge", http.StatusNotImplemented, ""},
+		{"known method not rejected", []NormalizeMethodOption{WithRejectUnknownMethods(true)}, "options", http.StatusOK, "OPTIONS"},
+		{"custom method not rejected", []NormalizeMethodOption{WithRejectUnknownMethods(true), WithKnownMethods("PURGE")}, "Purge", http.StatusOK, "PURGE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method string
+			handler := NormalizeMethod(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedMethod, method)
+		})
+	}
+}
+
+func TestNormalizeMethod_Routing(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("posted"))
+	})
+
+	req := httptest.NewRequest("post", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	NormalizeMethod()(mux).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "posted", rr.Body.String())
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type negotiatedTypeKey struct{}
+
+// Negotiate is a middleware that selects the best media type to respond with
+// from the given offers (e.g. "application/json"), based on the request's
+// Accept header. The selected type can be retrieved by handlers using
+// NegotiatedType. Offers earlier in the list are preferred when the client has
+// no preference between them.
+//
+// Wildcards in the Accept header (e.g. "text/*" and "*/*") are supported, with
+// more specific ranges taking precedence. Requests with no Accept header are
+// treated as accepting anything.
+//
+// If none of the offers are acceptable, the request is responded to with a 406
+// Not Acceptable response. Chain this middleware with ErrorHandler to
+// customise this.
+func Negotiate(offers ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addVary(w.Header(), "Accept")
+
+			accept := r.Header.Values("Accept")
+			if len(accept) == 0 {
+				accept = []string{"*/*"}
+			}
+
+			selected := selectOffer(parseMediaRanges(accept), offers)
+			if selected == "" {
+				http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), negotiatedTypeKey{}, selected)))
+		})
+	}
+}
+
+// NegotiatedType returns the media type selected by the Negotiate middleware,
+// or an empty string if the middleware was not used.
+func NegotiatedType(r *http.Request) string {
+	if t, ok := r.Context().Value(negotiatedTypeKey{}).(string); ok {
+		return t
+	}
+	return ""
+}
+
+func parseMediaRanges(accept []string) map[string]float64 {
+	ranges := make(map[string]float64)
+	for i := range accept {
+		parts := strings.Split(accept[i], ",")
+		for p := range parts {
+			params := strings.Split(parts[p], ";")
+			mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+			if mediaType == "" {
+				continue
+			}
+
+			value := 1.0
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					value, _ = strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				}
+			}
+			ranges[mediaType] = value
+		}
+	}
+	return ranges
+}
+
+func selectOffer(ranges map[string]float64, offers []string) string {
+	best := ""
+	bestValue := 0.0
+	for _, offer := range offers {
+		mediaType := strings.ToLower(offer)
+		mainType, _, _ := strings.Cut(mediaType, "/")
+
+		value, ok := ranges[mediaType]
+		if !ok {
+			value, ok = ranges[mainType+"/*"]
+		}
+		if !ok {
+			value = ranges["*/*"]
+		}
+
+		if value > bestValue {
+			best = offer
+			bestValue = value
+		}
+	}
+	return best
+}
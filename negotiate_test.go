@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   []string
+		offers   []string
+		expected string
+	}{
+		{"no accept header", nil, []string{"application/json", "text/html"}, "application/json"},
+		{"exact match", []string{"text/html"}, []string{"application/json", "text/html"}, "text/html"},
+		{"q values", []string{"application/json;q=0.5, text/html"}, []string{"application/json", "text/html"}, "text/html"},
+		{"subtype wildcard", []string{"text/*"}, []string{"application/json", "text/plain"}, "text/plain"},
+		{"full wildcard", []string{"*/*"}, []string{"application/json", "text/html"}, "application/json"},
+		{"more specific wins", []string{"text/*;q=0.9, text/plain;q=0.1"}, []string{"text/plain", "text/html"}, "text/html"},
+		{"case insensitive", []string{"TEXT/HTML"}, []string{"text/html"}, "text/html"},
+		{"multiple header values", []string{"application/xml;q=0.1", "text/html;q=0.9"}, []string{"application/xml", "text/html"}, "text/html"},
+		{"zero weight excluded", []string{"text/html;q=0, */*;q=0.1"}, []string{"text/html", "application/json"}, "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var negotiated string
+			handler := Negotiate(tt.offers...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				negotiated = NegotiatedType(r)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			for _, v := range tt.accept {
+				req.Header.Add("Accept", v)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.expected, negotiated)
+			assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestNegotiate_NotAcceptable(t *testing.T) {
+	called := false
+	handler := Negotiate("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+}
+
+func TestNegotiate_ErrorHandler(t *testing.T) {
+	handler := ErrorHandler(WithErrorHandler(http.StatusNotAcceptable, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable)
+		w.Write([]byte("custom"))
+	})))(Negotiate("application/json")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept", "text/html")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	assert.Equal(t, "custom", rr.Body.String())
+}
+
+func TestNegotiatedType_WithoutMiddleware(t *testing.T) {
+	assert.Equal(t, "", NegotiatedType(httptest.NewRequest("GET", "/test", nil)))
+}
+
+func TestParseMediaRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected map[string]float64
+	}{
+		{
+			name:     "single type",
+			input:    []string{"text/html"},
+			expected: map[string]float64{"text/html": 1.0},
+		},
+		{
+			name:     "single type with weight",
+			input:    []string{"text/html;q=0.8"},
+			expected: map[string]float64{"text/html": 0.8},
+		},
+		{
+			name:     "multiple types single header",
+			input:    []string{"text/html;q=0.8, application/json;q=0.9"},
+			expected: map[string]float64{"text/html": 0.8, "application/json": 0.9},
+		},
+		{
+			name:     "multiple header values",
+			input:    []string{"text/html;q=0.8", "application/json;q=0.9"},
+			expected: map[string]float64{"text/html": 0.8, "application/json": 0.9},
+		},
+		{
+			name:     "zero weight",
+			input:    []string{"text/html;q=0"},
+			expected: map[string]float64{"text/html": 0.0},
+		},
+		{
+			name:     "wildcards",
+			input:    []string{"text/*;q=0.5, */*;q=0.1"},
+			expected: map[string]float64{"text/*": 0.5, "*/*": 0.1},
+		},
+		{
+			name:     "case insensitive type",
+			input:    []string{"TEXT/HTML, Application/JSON"},
+			expected: map[string]float64{"text/html": 1.0, "application/json": 1.0},
+		},
+		{
+			name:     "whitespace handling",
+			input:    []string{" text/html ; q=0.8 , application/json "},
+			expected: map[string]float64{"text/html": 0.8, "application/json": 1.0},
+		},
+		{
+			name:     "other parameters",
+			input:    []string{"text/html;level=1;q=0.7"},
+			expected: map[string]float64{"text/html": 0.7},
+		},
+		{
+			name:     "invalid q value",
+			input:    []string{"text/html;q=invalid"},
+			expected: map[string]float64{"text/html": 0.0},
+		},
+		{
+			name:     "complex real world example",
+			input:    []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+			expected: map[string]float64{"text/html": 1.0, "application/xhtml+xml": 1.0, "application/xml": 0.9, "*/*": 0.8},
+		},
+		{
+			name:     "empty input",
+			input:    []string{},
+			expected: map[string]float64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseMediaRanges(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
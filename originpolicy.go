@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+type originMethodPolicyConfig struct {
+	origins  map[string]map[string]bool
+	defaults map[string]bool
+}
+
+type OriginMethodPolicyOption func(*originMethodPolicyConfig)
+
+// WithOriginMethods sets the methods that requests from the given origin
+// (e.g. "https://example.com") are allowed to use. Origins are matched
+// case-insensitively.
+func WithOriginMethods(origin string, methods ...string) OriginMethodPolicyOption {
+	return func(config *originMethodPolicyConfig) {
+		config.origins[strings.ToLower(origin)] = methodSet(methods)
+	}
+}
+
+// WithDefaultOriginMethods sets the methods that requests from origins not
+// configured with WithOriginMethods are allowed to use. By default, such
+// requests may use any method.
+func WithDefaultOriginMethods(methods ...string) OriginMethodPolicyOption {
+	return func(config *originMethodPolicyConfig) {
+		config.defaults = methodSet(methods)
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
+
+// OriginMethodPolicy is a middleware that restricts the methods requests may
+// use based on their Origin header. For example, a partner's site could be
+// allowed to make cross-origin GET requests but not POST requests.
+//
+// Requests without an Origin header are always allowed. Note that browsers
+// send an Origin header with same-origin requests that use methods other than
+// GET and HEAD, so if WithDefaultOriginMethods is used then the site's own
+// origin should usually be configured with WithOriginMethods.
+//
+// Requests using a method that isn't permitted for their origin are responded
+// to with a 403 response with no body. Chain this middleware with
+// ErrorHandler to customise this.
+func OriginMethodPolicy(opts ...OriginMethodPolicyOption) func(http.Handler) http.Handler {
+	config := &originMethodPolicyConfig{
+		origins: make(map[string]map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, ok := config.origins[strings.ToLower(origin)]
+			if !ok {
+				allowed = config.defaults
+			}
+
+			if allowed != nil && !allowed[r.Method] {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginMethodPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []OriginMethodPolicyOption
+		origin         string
+		method         string
+		expectedStatus int
+	}{
+		{
+			name:           "allowed method",
+			opts:           []OriginMethodPolicyOption{WithOriginMethods("https://partner.example", http.MethodGet)},
+			origin:         "https://partner.example",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "disallowed method",
+			opts:           []OriginMethodPolicyOption{WithOriginMethods("https://partner.example", http.MethodGet)},
+			origin:         "https://partner.example",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "origin matched case-insensitively",
+			opts:           []OriginMethodPolicyOption{WithOriginMethods("https://Partner.example", http.MethodGet)},
+			origin:         "https://partner.EXAMPLE",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unconfigured origin unrestricted by default",
+			opts:           []OriginMethodPolicyOption{WithOriginMethods("https://partner.example", http.MethodGet)},
+			origin:         "https://other.example",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "unconfigured origin with defaults",
+			opts: []OriginMethodPolicyOption{
+				WithOriginMethods("https://partner.example", http.MethodGet, http.MethodPost),
+				WithDefaultOriginMethods(http.MethodGet, http.MethodHead),
+			},
+			origin:         "https://other.example",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "configured origin overrides defaults",
+			opts: []OriginMethodPolicyOption{
+				WithOriginMethods("https://partner.example", http.MethodGet, http.MethodPost),
+				WithDefaultOriginMethods(http.MethodGet, http.MethodHead),
+			},
+			origin:         "https://partner.example",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no origin",
+			opts:           []OriginMethodPolicyOption{WithDefaultOriginMethods(http.MethodGet)},
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no methods allowed",
+			opts:           []OriginMethodPolicyOption{WithOriginMethods("https://evil.example")},
+			origin:         "https://evil.example",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := OriginMethodPolicy(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, called)
+		})
+	}
+}
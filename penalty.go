@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type penaltyLimitConfig struct {
+	statuses  map[int]bool
+	threshold int
+	window    time.Duration
+	key       func(*http.Request) string
+	clock     func() time.Time
+}
+
+type PenaltyLimitOption func(*penaltyLimitConfig)
+
+// WithPenaltyStatuses sets the response status codes that count as failures.
+// Defaults to 401 Unauthorized and 403 Forbidden.
+func WithPenaltyStatuses(statuses ...int) PenaltyLimitOption {
+	return func(config *penaltyLimitConfig) {
+		config.statuses = make(map[int]bool)
+		for i := range statuses {
+			config.statuses[statuses[i]] = true
+		}
+	}
+}
+
+// WithPenaltyThreshold sets the number of failures a client can have within
+// the window before they are blocked. Defaults to 10. PenaltyLimit panics if
+// the threshold is less than 1.
+func WithPenaltyThreshold(threshold int) PenaltyLimitOption {
+	return func(config *penaltyLimitConfig) {
+		config.threshold = threshold
+	}
+}
+
+// WithPenaltyWindow sets the length of the window in which failures are
+// counted. Defaults to 15 minutes.
+func WithPenaltyWindow(window time.Duration) PenaltyLimitOption {
+	return func(config *penaltyLimitConfig) {
+		config.window = window
+	}
+}
+
+// WithPenaltyKey sets a function that determines which client a request
+// belongs to. By default, requests are grouped by the IP address in RemoteAddr.
+func WithPenaltyKey(key func(*http.Request) string) PenaltyLimitOption {
+	return func(config *penaltyLimitConfig) {
+		config.key = key
+	}
+}
+
+// PenaltyLimit is a middleware that blocks clients that cause too many failed
+// responses (such as 401 Unauthorized) within a window of time, to discourage
+// brute-force attacks. Successful responses don't count towards the limit.
+//
+// Once a client has reached the threshold, their requests are responded to
+// with a 429 Too Many Requests response with a Retry-After header, without
+// calling the next handler, until enough failures have left the window.
+//
+// If RealAddress is used it should be applied before this middleware, so that
+// clients are identified correctly.
+func PenaltyLimit(opts ...PenaltyLimitOption) func(http.Handler) http.Handler {
+	config := &penaltyLimitConfig{
+		statuses: map[int]bool{
+			http.StatusUnauthorized: true,
+			http.StatusForbidden:    true,
+		},
+		threshold: 10,
+		window:    15 * time.Minute,
+		key:       defaultRateLimitKey,
+		clock:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.threshold < 1 {
+		panic("middleware: penalty threshold must be at least 1, got " + strconv.Itoa(config.threshold))
+	}
+
+	failures := &slidingWindowLimiter{
+		limit:     config.threshold,
+		window:    config.window,
+		requests:  make(map[string][]time.Time),
+		lastSweep: config.clock(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := config.key(r)
+			if wait, blocked := failures.blocked(key, config.clock()); blocked {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			wrapped := &statusRecorder{
+				ResponseWriter: w,
+			}
+
+			next.ServeHTTP(wrapped, r)
+
+			if config.statuses[wrapped.status] {
+				failures.add(key, config.clock())
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withPenaltyClock(clock func() time.Time) PenaltyLimitOption {
+	return func(config *penaltyLimitConfig) {
+		config.clock = clock
+	}
+}
+
+func TestPenaltyLimit_RepeatedFailures(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	calls := 0
+	handler := PenaltyLimit(
+		WithPenaltyThreshold(3),
+		WithPenaltyWindow(time.Minute),
+		withPenaltyClock(func() time.Time { return now }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/login", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("Authorization", auth)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, serve("wrong").Code)
+		now = now.Add(10 * time.Second)
+	}
+
+	// Blocked, even with the correct credentials
+	rr := serve("secret")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, 3, calls)
+
+	// Once the first failure leaves the window, requests are allowed again
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusOK, serve("secret").Code)
+	assert.Equal(t, 4, calls)
+}
+
+func TestPenaltyLimit_SuccessesNotCounted(t *testing.T) {
+	handler := PenaltyLimit(WithPenaltyThreshold(2))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+}
+
+func TestPenaltyLimit_CustomStatuses(t *testing.T) {
+	handler := PenaltyLimit(
+		WithPenaltyThreshold(1),
+		WithPenaltyStatuses(http.StatusNotFound),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	serve := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve("/forbidden"))
+	assert.Equal(t, http.StatusForbidden, serve("/forbidden"))
+	assert.Equal(t, http.StatusNotFound, serve("/missing"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("/forbidden"))
+}
+
+func TestPenaltyLimit_SeparateClients(t *testing.T) {
+	handler := PenaltyLimit(WithPenaltyThreshold(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	serve := func(addr string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:1234"))
+	assert.Equal(t, http.StatusUnauthorized, serve("192.0.2.2:1234"))
+}
+
+func TestPenaltyLimit_CustomKey(t *testing.T) {
+	handler := PenaltyLimit(
+		WithPenaltyThreshold(1),
+		WithPenaltyKey(func(r *http.Request) string { return r.URL.Query().Get("user") }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	serve := func(user string) int {
+		req := httptest.NewRequest("GET", "/login?user="+user, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("alice"))
+	assert.Equal(t, http.StatusUnauthorized, serve("bob"))
+}
+
+func TestPenaltyLimit_InvalidThreshold(t *testing.T) {
+	for _, threshold := range []int{0, -1} {
+		assert.PanicsWithValue(t, "middleware: penalty threshold must be at least 1, got "+strconv.Itoa(threshold), func() {
+			PenaltyLimit(WithPenaltyThreshold(threshold))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import "net/http"
+
+type preflightOKConfig struct {
+	headers map[string][]string
+}
+
+type PreflightOKOption func(*preflightOKConfig)
+
+// WithPreflightHeader specifies one header to be added to preflight responses,
+// such as Allow or Access-Control-Allow-Methods. The same key can be used
+// multiple times, resulting in multiple headers being set.
+func WithPreflightHeader(key, value string) PreflightOKOption {
+	return func(config *preflightOKConfig) {
+		config.headers[key] = append(config.headers[key], value)
+	}
+}
+
+// PreflightOK is a middleware that responds to CORS preflight requests (OPTIONS
+// requests with an Access-Control-Request-Method header) with a 204 response,
+// without calling the next handler. Any headers configured with
+// WithPreflightHeader are added to the response.
+//
+// This is intended for setups where CORS is handled elsewhere (e.g. by a CDN),
+// but the origin server still needs to answer preflight requests. Other
+// requests, including OPTIONS requests that aren't preflights, are passed to
+// the next handler.
+func PreflightOK(opts ...PreflightOKOption) func(http.Handler) http.Handler {
+	config := &preflightOKConfig{
+		headers: make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for k := range config.headers {
+				for _, v := range config.headers[k] {
+					w.Header().Add(k, v)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflightOK(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		requestMethod  string
+		expectedStatus int
+		expectedCalled bool
+	}{
+		{"preflight", "OPTIONS", "POST", http.StatusNoContent, false},
+		{"non-preflight OPTIONS", "OPTIONS", "", http.StatusOK, true},
+		{"GET", "GET", "", http.StatusOK, true},
+		{"GET with request method header", "GET", "POST", http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := PreflightOK(
+				WithPreflightHeader("Allow", "GET, POST, OPTIONS"),
+				WithPreflightHeader("Access-Control-Allow-Methods", "GET, POST"),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedCalled, called)
+			if !tt.expectedCalled {
+				assert.Equal(t, "GET, POST, OPTIONS", rr.Header().Get("Allow"))
+				assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestPreflightOK_NoHeaders(t *testing.T) {
+	handler := PreflightOK()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header())
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PreloadLink describes a resource that should be preloaded by the Preload
+// middleware.
+type PreloadLink struct {
+	// URL is the URL of the resource to preload.
+	URL string
+	// As is the type of the resource, e.g. "script", "style" or "font".
+	As string
+	// CrossOrigin is the CORS mode to use when fetching the resource, e.g.
+	// "anonymous" or "use-credentials". If empty, the attribute is omitted.
+	CrossOrigin string
+	// When is an optional predicate that determines whether the link should be
+	// included for a response. It is called just before the headers are
+	// written, so can inspect the headers set by the handler. If nil, the link
+	// is always included.
+	When func(r *http.Request, header http.Header) bool
+}
+
+func (p PreloadLink) String() string {
+	link := fmt.Sprintf("<%s>; rel=preload", p.URL)
+	if p.As != "" {
+		link += "; as=" + p.As
+	}
+	if p.CrossOrigin != "" {
+		link += "; crossorigin=" + p.CrossOrigin
+	}
+	return link
+}
+
+// Preload adds a Link header to responses with a "preload" hint for each of the
+// given links, allowing browsers to start fetching the resources before they
+// find them in the response body.
+//
+// The header is added when the response headers are written, so links can be
+// made conditional on the response (e.g. only preloading resources for HTML
+// pages) using PreloadLink.When. Any Link headers set by the handler are kept.
+func Preload(links ...PreloadLink) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &preloadWrapper{
+				ResponseWriter: w,
+				req:            r,
+				links:          links,
+			}
+			next.ServeHTTP(wrapped, r)
+
+			if !wrapped.headers {
+				// The server will write the headers once we return
+				wrapped.addLinks()
+			}
+		})
+	}
+}
+
+type preloadWrapper struct {
+	http.ResponseWriter
+	req     *http.Request
+	links   []PreloadLink
+	headers bool
+}
+
+func (p *preloadWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		p.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	p.headers = true
+	p.addLinks()
+	p.ResponseWriter.WriteHeader(code)
+}
+
+func (p *preloadWrapper) Write(b []byte) (int, error) {
+	if !p.headers {
+		p.WriteHeader(http.StatusOK)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+func (p *preloadWrapper) Flush() {
+	if !p.headers {
+		p.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := p.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// addLinks adds a single Link header containing all applicable links.
+func (p *preloadWrapper) addLinks() {
+	header := p.ResponseWriter.Header()
+
+	var values []string
+	for i := range p.links {
+		if p.links[i].When == nil || p.links[i].When(p.req, header) {
+			values = append(values, p.links[i].String())
+		}
+	}
+
+	if len(values) > 0 {
+		header.Add("Link", strings.Join(values, ", "))
+	}
+}
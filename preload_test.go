@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isHTML(_ *http.Request, header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "text/html")
+}
+
+func TestPreload_CombinesLinks(t *testing.T) {
+	handler := Preload(
+		PreloadLink{URL: "/app.js", As: "script"},
+		PreloadLink{URL: "/style.css", As: "style"},
+		PreloadLink{URL: "https://fonts.example.com/font.woff2", As: "font", CrossOrigin: "anonymous"},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, []string{
+		"</app.js>; rel=preload; as=script, </style.css>; rel=preload; as=style, <https://fonts.example.com/font.woff2>; rel=preload; as=font; crossorigin=anonymous",
+	}, rr.Header().Values("Link"))
+	assert.Equal(t, "hello", rr.Body.String())
+}
+
+func TestPreload_KeepsExistingLinks(t *testing.T) {
+	handler := Preload(
+		PreloadLink{URL: "/app.js", As: "script"},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "<https://example.com/>; rel=canonical")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, []string{
+		"<https://example.com/>; rel=canonical",
+		"</app.js>; rel=preload; as=script",
+	}, rr.Header().Values("Link"))
+}
+
+func TestPreload_Conditional(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		expected    []string
+	}{
+		{
+			name:        "html",
+			contentType: "text/html; charset=utf-8",
+			expected:    []string{"</app.js>; rel=preload; as=script, </style.css>; rel=preload; as=style"},
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			expected:    []string{"</style.css>; rel=preload; as=style"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Preload(
+				PreloadLink{URL: "/app.js", As: "script", When: isHTML},
+				PreloadLink{URL: "/style.css", As: "style"},
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte("content"))
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tt.expected, rr.Header().Values("Link"))
+		})
+	}
+}
+
+func TestPreload_NoApplicableLinks(t *testing.T) {
+	handler := Preload(
+		PreloadLink{URL: "/app.js", As: "script", When: isHTML},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	assert.Empty(t, rr.Header().Values("Link"))
+}
+
+func TestPreload_NoBody(t *testing.T) {
+	handler := Preload(
+		PreloadLink{URL: "/app.js", As: "script"},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, "</app.js>; rel=preload; as=script", rr.Header().Get("Link"))
+}
+
+func TestPreload_Informational(t *testing.T) {
+	handler := Preload(PreloadLink{URL: "/app.js", As: "script"})(earlyHintsHandler)
+
+	informational, res, body := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, []string{"</style.css>; rel=preload"}, informational[http.StatusEarlyHints].Values("Link"))
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"</style.css>; rel=preload", "</app.js>; rel=preload; as=script"}, res.Header.Values("Link"))
+	assert.Equal(t, "test content", body)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// DefaultQueryParams is a middleware that adds default values for query
+// parameters that are missing from requests, so that handlers don't each need
+// to deal with clients omitting them.
+//
+// A parameter is only defaulted if it is entirely absent from the query
+// string; parameters present with an empty value are left alone. Defaults are
+// appended to the existing query string, so the order and encoding of the
+// client's parameters are preserved.
+func DefaultQueryParams(defaults map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+
+			missing := url.Values{}
+			for key, value := range defaults {
+				if _, ok := query[key]; !ok {
+					missing.Set(key, value)
+				}
+			}
+
+			if len(missing) > 0 {
+				if r.URL.RawQuery == "" {
+					r.URL.RawQuery = missing.Encode()
+				} else {
+					r.URL.RawQuery += "&" + missing.Encode()
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
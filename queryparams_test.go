@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultQueryParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults map[string]string
+		target   string
+		expected string
+	}{
+		{
+			name:     "absent",
+			defaults: map[string]string{"format": "json"},
+			target:   "/search",
+			expected: "format=json",
+		},
+		{
+			name:     "absent with other params",
+			defaults: map[string]string{"format": "json"},
+			target:   "/search?q=go&page=2",
+			expected: "q=go&page=2&format=json",
+		},
+		{
+			name:     "present",
+			defaults: map[string]string{"format": "json"},
+			target:   "/search?format=xml",
+			expected: "format=xml",
+		},
+		{
+			name:     "present but empty",
+			defaults: map[string]string{"format": "json"},
+			target:   "/search?format=",
+			expected: "format=",
+		},
+		{
+			name:     "multiple defaults",
+			defaults: map[string]string{"format": "json", "page": "1", "lang": "en gb"},
+			target:   "/search?page=3",
+			expected: "page=3&format=json&lang=en+gb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rawQuery string
+			handler := DefaultQueryParams(tt.defaults)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rawQuery = r.URL.RawQuery
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, tt.expected, rawQuery)
+		})
+	}
+}
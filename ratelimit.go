@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type rateLimitConfig struct {
+	tokens   float64
+	interval time.Duration
+	key      func(*http.Request) string
+	cost     func(*http.Request) int
+	clock    func() time.Time
+	debug    *DebugRegistry
+}
+
+type RateLimitOption func(*rateLimitConfig)
+
+// WithRate sets the rate at which requests are allowed: each client may spend
+// up to the given number of tokens per interval. This is also the maximum
+// number of tokens a client can accumulate. Defaults to 60 tokens per minute.
+func WithRate(tokens int, interval time.Duration) RateLimitOption {
+	return func(config *rateLimitConfig) {
+		config.tokens = float64(tokens)
+		config.interval = interval
+	}
+}
+
+// WithRateLimitKey sets a function that determines which client a request
+// belongs to. Each client has a separate allowance. By default, requests are
+// grouped by the IP address in RemoteAddr.
+func WithRateLimitKey(key func(*http.Request) string) RateLimitOption {
+	return func(config *rateLimitConfig) {
+		config.key = key
+	}
+}
+
+// WithCostFunc sets a function that determines how many tokens a request
+// costs, allowing expensive endpoints to be weighted more heavily than cheap
+// ones. By default, every request costs one token.
+func WithCostFunc(cost func(*http.Request) int) RateLimitOption {
+	return func(config *rateLimitConfig) {
+		config.cost = cost
+	}
+}
+
+// WithRateLimitDebugRegistry registers the rate limit's settings with the given
+// registry, so they are included in the output of DebugConfig.
+func WithRateLimitDebugRegistry(registry *DebugRegistry) RateLimitOption {
+	return func(config *rateLimitConfig) {
+		config.debug = registry
+	}
+}
+
+// RateLimit is a middleware that limits the rate of requests from each client
+// using a token bucket.
+//
+// Each request consumes a number of tokens (one by default, or as determined
+// by WithCostFunc), and tokens are replenished at the rate configured with
+// WithRate. Requests that can't afford their cost are responded to with a 429
+// Too Many Requests response with a Retry-After header, without calling the
+// next handler. Requests that cost more than the maximum number of tokens can
+// never be afforded, so are always rejected with a 429 response without a
+// Retry-After header.
+func RateLimit(opts ...RateLimitOption) func(http.Handler) http.Handler {
+	config := &rateLimitConfig{
+		tokens:   60,
+		interval: time.Minute,
+		key:      defaultRateLimitKey,
+		cost:     func(*http.Request) int { return 1 },
+		clock:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	config.debug.Register("RateLimit", map[string]any{
+		"tokens":   config.tokens,
+		"interval": config.interval.String(),
+	})
+
+	limiter := &rateLimiter{
+		conf:      config,
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: config.clock(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wait, ok := limiter.take(config.key(r), float64(config.cost(r))); !ok {
+				if wait > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func defaultRateLimitKey(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type rateLimiter struct {
+	conf      *rateLimitConfig
+	mutex     sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// take attempts to take the given number of tokens from the key's bucket. If
+// there aren't enough tokens, returns false and how long it will be until
+// there are. If the cost is more than the bucket can ever hold, returns false
+// and a wait of zero.
+func (l *rateLimiter) take(key string, cost float64) (time.Duration, bool) {
+	if cost > l.conf.tokens {
+		return 0, false
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.conf.clock()
+	rate := l.conf.tokens / float64(l.conf.interval)
+	l.sweep(now, rate)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.conf.tokens, updated: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.conf.tokens, bucket.tokens+float64(now.Sub(bucket.updated))*rate)
+	bucket.updated = now
+
+	if bucket.tokens < cost {
+		return time.Duration((cost - bucket.tokens) / rate), false
+	}
+
+	bucket.tokens -= cost
+	return 0, true
+}
+
+// sweep removes any buckets that would have been fully replenished, at most
+// once per interval.
+func (l *rateLimiter) sweep(now time.Time, rate float64) {
+	if now.Sub(l.lastSweep) < l.conf.interval {
+		return
+	}
+	l.lastSweep = now
+
+	for k, bucket := range l.buckets {
+		if bucket.tokens+float64(now.Sub(bucket.updated))*rate >= l.conf.tokens {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withRateLimitClock(clock func() time.Time) RateLimitOption {
+	return func(config *rateLimitConfig) {
+		config.clock = clock
+	}
+}
+
+func TestRateLimit_Basic(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := RateLimit(
+		WithRate(3, time.Minute),
+		withRateLimitClock(func() time.Time { return now }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve().Code)
+	}
+
+	rr := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "20", rr.Header().Get("Retry-After"))
+
+	now = now.Add(time.Second * 20)
+	assert.Equal(t, http.StatusOK, serve().Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve().Code)
+}
+
+func TestRateLimit_SeparateClients(t *testing.T) {
+	handler := RateLimit(WithRate(1, time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(addr string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:5678"))
+	assert.Equal(t, http.StatusOK, serve("192.0.2.2:1234"))
+}
+
+func TestRateLimit_CostFunc(t *testing.T) {
+	handler := RateLimit(
+		WithRate(10, time.Minute),
+		WithCostFunc(func(r *http.Request) int {
+			if r.URL.Path == "/export" {
+				return 10
+			}
+			return 1
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/ping"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("/export"))
+	for i := 0; i < 9; i++ {
+		assert.Equal(t, http.StatusOK, serve("/ping"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serve("/ping"))
+}
+
+func TestRateLimit_CostExceedsCapacity(t *testing.T) {
+	handler := RateLimit(
+		WithRate(10, time.Minute),
+		WithCostFunc(func(r *http.Request) int {
+			if r.URL.Path == "/huge" {
+				return 11
+			}
+			return 1
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/huge")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+
+	// The rejected request doesn't consume any tokens
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, serve("/ping").Code)
+	}
+
+	rr = serve("/ping")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestRateLimit_CustomKey(t *testing.T) {
+	handler := RateLimit(
+		WithRate(1, time.Minute),
+		WithRateLimitKey(func(r *http.Request) string {
+			return r.Header.Get("X-API-Key")
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(key string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("a"))
+	assert.Equal(t, http.StatusOK, serve("b"))
+}
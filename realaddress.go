@@ -8,6 +8,10 @@ import (
 
 type realAddressConfig struct {
 	trustedProxies []net.IPNet
+	preservePort   bool
+	rejectBogons   bool
+	bogonFallback  bool
+	strict         bool
 }
 
 var defaultTrustedProxies = []net.IPNet{
@@ -19,6 +23,30 @@ var defaultTrustedProxies = []net.IPNet{
 	mustParseCIDR("fc00::/7"),
 }
 
+// bogons are the IP ranges that are reserved, private, or otherwise shouldn't
+// be seen as the source of traffic on the public internet.
+var bogons = []net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("127.0.0.0/8"),
+	mustParseCIDR("169.254.0.0/16"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("192.0.2.0/24"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("198.51.100.0/24"),
+	mustParseCIDR("203.0.113.0/24"),
+	mustParseCIDR("224.0.0.0/3"),
+	mustParseCIDR("::/127"),
+	mustParseCIDR("100::/64"),
+	mustParseCIDR("2001:db8::/32"),
+	mustParseCIDR("fc00::/7"),
+	mustParseCIDR("fe80::/10"),
+	mustParseCIDR("ff00::/8"),
+}
+
 type RealAddressOption func(*realAddressConfig)
 
 // WithTrustedProxies configures the IP ranges that RealAddress will accept
@@ -29,6 +57,51 @@ func WithTrustedProxies(trustedProxies []net.IPNet) RealAddressOption {
 	}
 }
 
+// WithPreservePort sets whether RealAddress should ensure RemoteAddr is always
+// in "host:port" form. Addresses taken from X-Forwarded-For headers don't
+// include a port, so when this is enabled they are given a placeholder port of
+// 0. Defaults to false, which leaves forwarded addresses as bare IPs.
+func WithPreservePort(preservePort bool) RealAddressOption {
+	return func(config *realAddressConfig) {
+		config.preservePort = preservePort
+	}
+}
+
+// WithRejectBogons sets whether RealAddress should reject requests where the
+// client address claimed in X-Forwarded-For is in a private or reserved range,
+// but the request subsequently passed through a public address. Such requests
+// have almost certainly had their X-Forwarded-For header spoofed. Rejected
+// requests receive a 403 response; chain this middleware with ErrorHandler to
+// customise this. Defaults to false.
+func WithRejectBogons(reject bool) RealAddressOption {
+	return func(config *realAddressConfig) {
+		config.rejectBogons = reject
+	}
+}
+
+// WithBogonFallback sets whether RealAddress should ignore X-Forwarded-For
+// and leave RemoteAddr unchanged in the circumstances described in
+// WithRejectBogons, instead of rejecting the request. Has no effect if
+// WithRejectBogons is enabled. Defaults to false.
+func WithBogonFallback(fallback bool) RealAddressOption {
+	return func(config *realAddressConfig) {
+		config.bogonFallback = fallback
+	}
+}
+
+// WithRealAddressStrict sets whether RealAddress should reject requests where
+// the X-Forwarded-For chain can't be parsed as far as the client's address.
+// By default, an unparseable hop added by a trusted proxy is silently skipped
+// and the last good address is used instead, which can hide a misconfigured
+// proxy. In strict mode such requests receive a 400 response; chain this
+// middleware with ErrorHandler to customise this. Malformed hops before the
+// client's address are ignored, as the client controls them. Defaults to false.
+func WithRealAddressStrict(strict bool) RealAddressOption {
+	return func(config *realAddressConfig) {
+		config.strict = strict
+	}
+}
+
 // RealAddress is a middleware that sets the RemoteAddr property on the http.Request
 // to the client's real IP address according to the X-Forwarded-For header.
 //
@@ -44,7 +117,32 @@ func RealAddress(opts ...RealAddressOption) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			r.RemoteAddr = selectRealAddress(collateForwardedHops(r), conf.trustedProxies)
+			hops := collateForwardedHops(r)
+			selected, ok := selectRealAddress(hops, conf.trustedProxies)
+			if !ok {
+				if conf.strict {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if selected == len(hops) {
+					// RemoteAddr itself couldn't be parsed, leave it alone
+					selected = len(hops) - 1
+				}
+			}
+			if (conf.rejectBogons || conf.bogonFallback) && spoofedBogon(hops, selected) {
+				if conf.rejectBogons {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				selected = len(hops) - 1
+			}
+
+			r.RemoteAddr = hops[selected]
+			if conf.preservePort {
+				if _, _, err := net.SplitHostPort(r.RemoteAddr); err != nil {
+					r.RemoteAddr = net.JoinHostPort(r.RemoteAddr, "0")
+				}
+			}
 
 			next.ServeHTTP(w, r)
 		})
@@ -64,15 +162,16 @@ func collateForwardedHops(r *http.Request) []string {
 	return res
 }
 
-func selectRealAddress(hops []string, trustedProxies []net.IPNet) string {
+// selectRealAddress returns the index of the hop that is the client's real
+// address. If a hop that needed to be examined couldn't be parsed, the index
+// of the last good hop is returned along with false; this is len(hops) if
+// even the final hop (from RemoteAddr) is bad.
+func selectRealAddress(hops []string, trustedProxies []net.IPNet) (int, bool) {
 	for i := len(hops) - 1; i >= 0; i-- {
 		trusted := false
 		ip := parseAddress(hops[i])
 		if ip == nil {
-			// If we can't parse the address at all, return the last good address.
-			// In theory this can panic if the first hop is bad, but that will always
-			// come from RemoteAddr.
-			return hops[i+1]
+			return i + 1, false
 		}
 
 		for j := range trustedProxies {
@@ -83,12 +182,36 @@ func selectRealAddress(hops []string, trustedProxies []net.IPNet) string {
 		}
 
 		if !trusted {
-			return hops[i]
+			return i, true
 		}
 	}
 
 	// Everything in the chain was trusted for some reason, just return the closest IP to the client
-	return hops[0]
+	return 0, true
+}
+
+// spoofedBogon determines whether the selected hop is a bogon address that
+// was forwarded by a hop with a public address.
+func spoofedBogon(hops []string, selected int) bool {
+	if !isBogon(parseAddress(hops[selected])) {
+		return false
+	}
+
+	for _, hop := range hops[selected+1:] {
+		if ip := parseAddress(hop); ip != nil && !isBogon(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBogon(ip net.IP) bool {
+	for i := range bogons {
+		if bogons[i].Contains(ip) {
+			return true
+		}
+	}
+	return false
 }
 
 func parseAddress(address string) net.IP {
@@ -159,3 +159,175 @@ func TestRealAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestRealAddress_PreservePort(t *testing.T) {
+	tests := []struct {
+		name         string
+		headers      []string
+		remoteAddr   string
+		expectedAddr string
+	}{
+		{"forwarded IPv4", []string{"203.0.113.1"}, "192.168.1.1:8080", "203.0.113.1:0"},
+		{"forwarded IPv6", []string{"2001:db8::1"}, "192.168.1.1:8080", "[2001:db8::1]:0"},
+		{"forwarded with port", []string{"203.0.113.1:1234"}, "192.168.1.1:8080", "203.0.113.1:1234"},
+		{"direct connection", nil, "203.0.113.50:8080", "203.0.113.50:8080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actualAddr string
+			var splitErr error
+			handler := RealAddress(WithPreservePort(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualAddr = r.RemoteAddr
+				_, _, splitErr = net.SplitHostPort(r.RemoteAddr)
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, header := range tt.headers {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expectedAddr, actualAddr)
+			assert.NoError(t, splitErr)
+		})
+	}
+}
+
+func TestRealAddress_Bogons(t *testing.T) {
+	publicProxies := []net.IPNet{mustParseCIDR("93.184.216.0/24")}
+
+	tests := []struct {
+		name           string
+		opts           []RealAddressOption
+		headers        []string
+		remoteAddr     string
+		expectedStatus int
+		expectedAddr   string
+	}{
+		{
+			name:           "spoofed private client allowed by default",
+			opts:           []RealAddressOption{WithTrustedProxies(publicProxies)},
+			headers:        []string{"10.0.0.1"},
+			remoteAddr:     "93.184.216.34:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "10.0.0.1",
+		},
+		{
+			name:           "spoofed private client rejected",
+			opts:           []RealAddressOption{WithTrustedProxies(publicProxies), WithRejectBogons(true)},
+			headers:        []string{"10.0.0.1"},
+			remoteAddr:     "93.184.216.34:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "spoofed private client falls back",
+			opts:           []RealAddressOption{WithTrustedProxies(publicProxies), WithBogonFallback(true)},
+			headers:        []string{"10.0.0.1"},
+			remoteAddr:     "93.184.216.34:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "93.184.216.34:8080",
+		},
+		{
+			name:           "reject takes precedence over fallback",
+			opts:           []RealAddressOption{WithTrustedProxies(publicProxies), WithRejectBogons(true), WithBogonFallback(true)},
+			headers:        []string{"10.0.0.1"},
+			remoteAddr:     "93.184.216.34:8080",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "bogon beyond untrusted hop is ignored",
+			opts:           []RealAddressOption{WithRejectBogons(true)},
+			headers:        []string{"203.0.113.7, 93.184.216.34"},
+			remoteAddr:     "192.168.1.1:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "93.184.216.34",
+		},
+		{
+			name:           "public client",
+			opts:           []RealAddressOption{WithTrustedProxies(publicProxies), WithRejectBogons(true)},
+			headers:        []string{"1.1.1.1"},
+			remoteAddr:     "93.184.216.34:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "1.1.1.1",
+		},
+		{
+			name:           "private client on private network",
+			opts:           []RealAddressOption{WithRejectBogons(true)},
+			headers:        []string{"10.0.0.1"},
+			remoteAddr:     "192.168.1.1:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "10.0.0.1",
+		},
+		{
+			name:           "direct private connection",
+			opts:           []RealAddressOption{WithRejectBogons(true)},
+			remoteAddr:     "10.0.0.1:8080",
+			expectedStatus: http.StatusOK,
+			expectedAddr:   "10.0.0.1:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actualAddr string
+			handler := RealAddress(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualAddr = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, header := range tt.headers {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedAddr, actualAddr)
+		})
+	}
+}
+
+func TestRealAddress_Strict(t *testing.T) {
+	tests := []struct {
+		name         string
+		strict       bool
+		headers      []string
+		remoteAddr   string
+		expectedCode int
+		expectedAddr string
+	}{
+		{"strict with valid chain", true, []string{"203.0.113.1, 192.168.1.2"}, "192.168.1.1:8080", http.StatusOK, "203.0.113.1"},
+		{"strict with malformed chain", true, []string{"203.0.113.1, not-an-ip, 192.168.1.2"}, "192.168.1.1:8080", http.StatusBadRequest, ""},
+		{"strict with empty hop", true, []string{"203.0.113.1,, 192.168.1.2"}, "192.168.1.1:8080", http.StatusBadRequest, ""},
+		{"strict with malformed client-supplied hop", true, []string{"not-an-ip, 203.0.113.1"}, "192.168.1.1:8080", http.StatusOK, "203.0.113.1"},
+		{"strict with malformed remote address", true, nil, "not-an-ip", http.StatusBadRequest, ""},
+		{"lenient with malformed chain", false, []string{"203.0.113.1, not-an-ip, 192.168.1.2"}, "192.168.1.1:8080", http.StatusOK, "192.168.1.2"},
+		{"lenient with malformed remote address", false, []string{"203.0.113.1"}, "not-an-ip", http.StatusOK, "not-an-ip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actualAddr string
+			handler := RealAddress(WithRealAddressStrict(tt.strict))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualAddr = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, header := range tt.headers {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedAddr, actualAddr)
+		})
+	}
+}
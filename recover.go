@@ -1,14 +1,32 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 )
 
+// redactedHeaders are request headers whose values are replaced when
+// including requests in logs, as they commonly contain credentials.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+}
+
+type requestDumpKey struct{}
+
 type RecoverPanicLogger func(r *http.Request, err any)
 
 type recoverConfig struct {
-	logger RecoverPanicLogger
+	logger      RecoverPanicLogger
+	reraise     bool
+	problemJSON bool
+	dump        bool
 }
 
 type RecoverOption func(*recoverConfig)
@@ -21,6 +39,40 @@ func WithPanicLogger(logger RecoverPanicLogger) RecoverOption {
 	}
 }
 
+// WithRecoverReraise sets whether Recover should re-panic after logging the
+// error, instead of sending a 500 response. This allows the panic to be dealt
+// with by the standard library or a top-level handler, which can be useful
+// during development. Defaults to false.
+func WithRecoverReraise(reraise bool) RecoverOption {
+	return func(config *recoverConfig) {
+		config.reraise = reraise
+	}
+}
+
+// WithRecoverProblemJSON sets whether Recover should respond with an RFC 7807
+// `application/problem+json` document when the request's Accept header
+// explicitly includes `application/json` or `application/problem+json`. If
+// the request has an X-Request-Id header, it is included as the problem's
+// instance. Defaults to false.
+func WithRecoverProblemJSON(problemJSON bool) RecoverOption {
+	return func(config *recoverConfig) {
+		config.problemJSON = problemJSON
+	}
+}
+
+// WithRecoverRequestDump sets whether Recover should make a dump of the
+// request available to the panic logger, to help diagnose panics that are
+// hard to reproduce. The dump contains the request line and headers (but not
+// the body), with the values of credential-bearing headers such as
+// Authorization and Cookie redacted. Custom loggers can retrieve it using
+// RecoverRequestDump; the default logger includes it automatically. Defaults
+// to false.
+func WithRecoverRequestDump(dump bool) RecoverOption {
+	return func(config *recoverConfig) {
+		config.dump = dump
+	}
+}
+
 // Recover is a middleware that will recover from downstream panics, log the
 // error, and send a 500 response to the client.
 func Recover(opts ...RecoverOption) func(http.Handler) http.Handler {
@@ -33,7 +85,18 @@ func Recover(opts ...RecoverOption) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					config.logger(r, err)
+					if config.dump {
+						config.logger(r.WithContext(context.WithValue(r.Context(), requestDumpKey{}, dumpRequest(r))), err)
+					} else {
+						config.logger(r, err)
+					}
+					if config.reraise {
+						panic(err)
+					}
+					if config.problemJSON && acceptsJSON(r) {
+						writeProblemJSON(w, r)
+						return
+					}
 					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				}
 			}()
@@ -44,5 +107,70 @@ func Recover(opts ...RecoverOption) func(http.Handler) http.Handler {
 }
 
 func defaultPanicLogger(r *http.Request, err any) {
+	if dump := RecoverRequestDump(r); dump != "" {
+		log.Printf("panic recovered: %v\n%s", err, dump)
+		return
+	}
 	log.Printf("panic recovered: %v", err)
 }
+
+// RecoverRequestDump returns the dump of the request made by Recover for its
+// panic logger, if WithRecoverRequestDump is enabled. Returns an empty string
+// otherwise.
+func RecoverRequestDump(r *http.Request) string {
+	if dump, ok := r.Context().Value(requestDumpKey{}).(string); ok {
+		return dump
+	}
+	return ""
+}
+
+// dumpRequest formats the request line and headers of the request, with
+// sorted headers and the values of any redactedHeaders replaced.
+func dumpRequest(r *http.Request) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %s %s\n", r.Method, r.URL.RequestURI(), r.Proto)
+	fmt.Fprintf(b, "Host: %s\n", r.Host)
+
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range r.Header[name] {
+			if redactedHeaders[http.CanonicalHeaderKey(name)] {
+				value = "[redacted]"
+			}
+			fmt.Fprintf(b, "%s: %s\n", name, value)
+		}
+	}
+	return b.String()
+}
+
+// acceptsJSON determines whether the request's Accept header explicitly
+// includes a JSON type.
+func acceptsJSON(r *http.Request) bool {
+	ranges := parseMediaRanges(r.Header.Values("Accept"))
+	return ranges["application/json"] > 0 || ranges["application/problem+json"] > 0
+}
+
+func writeProblemJSON(w http.ResponseWriter, r *http.Request) {
+	problem := struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Instance string `json:"instance,omitempty"`
+	}{
+		Type:     "about:blank",
+		Title:    http.StatusText(http.StatusInternalServerError),
+		Status:   http.StatusInternalServerError,
+		Instance: r.Header.Get("X-Request-Id"),
+	}
+
+	body, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write(body)
+}
@@ -66,3 +66,138 @@ func TestRecover_CustomLogger(t *testing.T) {
 	assert.Equal(t, req, loggedRequest)
 	assert.Equal(t, "custom error", loggedError)
 }
+
+func TestRecover_Reraise(t *testing.T) {
+	var loggedError any
+
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("reraised error")
+	})
+
+	handler := Recover(
+		WithPanicLogger(func(r *http.Request, err any) {
+			loggedError = err
+		}),
+		WithRecoverReraise(true),
+	)(nextHandler)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, "reraised error", func() {
+		handler.ServeHTTP(rr, req)
+	})
+	assert.Equal(t, "reraised error", loggedError)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestRecover_ProblemJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       string
+		requestID    string
+		expectedType string
+		expectedBody string
+	}{
+		{
+			name:         "json accepted",
+			accept:       "application/json",
+			expectedType: "application/problem+json",
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500}`,
+		},
+		{
+			name:         "problem+json accepted",
+			accept:       "text/html;q=0.9, application/problem+json",
+			expectedType: "application/problem+json",
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500}`,
+		},
+		{
+			name:         "with request ID",
+			accept:       "application/json",
+			requestID:    "abc123",
+			expectedType: "application/problem+json",
+			expectedBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"abc123"}`,
+		},
+		{
+			name:         "json not accepted",
+			accept:       "text/html, */*",
+			expectedType: "text/plain; charset=utf-8",
+			expectedBody: "Internal Server Error\n",
+		},
+		{
+			name:         "json refused",
+			accept:       "application/json;q=0",
+			expectedType: "text/plain; charset=utf-8",
+			expectedBody: "Internal Server Error\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Recover(
+				WithPanicLogger(func(r *http.Request, err any) {}),
+				WithRecoverProblemJSON(true),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				panic("something went wrong")
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept", tt.accept)
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-Id", tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusInternalServerError, rr.Code)
+			assert.Equal(t, tt.expectedType, rr.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestRecover_RequestDump(t *testing.T) {
+	var dump string
+
+	handler := Recover(
+		WithPanicLogger(func(r *http.Request, err any) {
+			dump = RecoverRequestDump(r)
+		}),
+		WithRecoverRequestDump(true),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	}))
+
+	req := httptest.NewRequest("POST", "http://example.com/test?q=1", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Cookie", "session=secret-session")
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "POST /test?q=1 HTTP/1.1\n"+
+		"Host: example.com\n"+
+		"Authorization: [redacted]\n"+
+		"Cookie: [redacted]\n"+
+		"User-Agent: test-agent\n", dump)
+	assert.NotContains(t, dump, "secret")
+}
+
+func TestRecover_RequestDumpDisabled(t *testing.T) {
+	dump := "unset"
+
+	handler := Recover(
+		WithPanicLogger(func(r *http.Request, err any) {
+			dump = RecoverRequestDump(r)
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.Empty(t, dump)
+}
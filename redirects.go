@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type relativeRedirectsConfig struct {
+	base string
+}
+
+type RelativeRedirectsOption func(*relativeRedirectsConfig)
+
+// WithRedirectBase sets a path prefix that RelativeRedirects adds to the
+// Location of redirects, e.g. when a proxy strips a prefix from request paths
+// before they reach the handler. It is applied to redirects with an absolute
+// path (such as "/login"), including those rewritten from absolute URLs.
+func WithRedirectBase(base string) RelativeRedirectsOption {
+	return func(config *relativeRedirectsConfig) {
+		config.base = strings.TrimSuffix(base, "/")
+	}
+}
+
+// RelativeRedirects is a middleware that rewrites the Location header of
+// redirect (3xx) responses from absolute URLs on the same host as the request
+// to paths, so that they're resolved against whatever address the client
+// used. This helps when a handler builds redirect URLs from the host it sees,
+// which differs from the one the client sees because of a proxy.
+//
+// Locations pointing to other hosts, and relative locations, are left alone
+// (apart from the prefix added by WithRedirectBase).
+func RelativeRedirects(opts ...RelativeRedirectsOption) func(http.Handler) http.Handler {
+	config := &relativeRedirectsConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&relativeRedirectsWrapper{
+				ResponseWriter: w,
+				req:            r,
+				conf:           config,
+			}, r)
+		})
+	}
+}
+
+type relativeRedirectsWrapper struct {
+	http.ResponseWriter
+	req     *http.Request
+	conf    *relativeRedirectsConfig
+	headers bool
+}
+
+func (rr *relativeRedirectsWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		rr.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	rr.headers = true
+	if code >= 300 && code <= 399 {
+		if location := rr.ResponseWriter.Header().Get("Location"); location != "" {
+			rr.ResponseWriter.Header().Set("Location", rr.rewrite(location))
+		}
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+// rewrite converts the location to a path if it's on the same host as the
+// request, and adds the configured base to absolute paths.
+func (rr *relativeRedirectsWrapper) rewrite(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || u.Opaque != "" {
+		return location
+	}
+
+	if u.Host != "" {
+		if !strings.EqualFold(u.Host, rr.req.Host) {
+			return location
+		}
+
+		u.Scheme = ""
+		u.Host = ""
+		u.User = nil
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+
+	if !strings.HasPrefix(u.Path, "/") {
+		// Relative path, or just a query/fragment
+		return location
+	}
+
+	result := u.String()
+	if rr.conf.base != "" {
+		result = rr.conf.base + result
+	}
+	return result
+}
+
+func (rr *relativeRedirectsWrapper) Write(b []byte) (int, error) {
+	if !rr.headers {
+		rr.WriteHeader(http.StatusOK)
+	}
+	return rr.ResponseWriter.Write(b)
+}
+
+func (rr *relativeRedirectsWrapper) Flush() {
+	if !rr.headers {
+		rr.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := rr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func redirectHandler(location string, code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", location)
+		w.WriteHeader(code)
+	})
+}
+
+func TestRelativeRedirects(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []RelativeRedirectsOption
+		location string
+		code     int
+		expected string
+	}{
+		{
+			name:     "absolute same host",
+			location: "http://example.com/login?next=%2Fhome#form",
+			code:     http.StatusFound,
+			expected: "/login?next=%2Fhome#form",
+		},
+		{
+			name:     "absolute same host with different case",
+			location: "https://EXAMPLE.com/login",
+			code:     http.StatusSeeOther,
+			expected: "/login",
+		},
+		{
+			name:     "absolute same host without path",
+			location: "http://example.com",
+			code:     http.StatusMovedPermanently,
+			expected: "/",
+		},
+		{
+			name:     "absolute cross host",
+			location: "https://other.example.com/login",
+			code:     http.StatusFound,
+			expected: "https://other.example.com/login",
+		},
+		{
+			name:     "absolute same host with different port",
+			location: "http://example.com:8080/login",
+			code:     http.StatusFound,
+			expected: "http://example.com:8080/login",
+		},
+		{
+			name:     "scheme relative same host",
+			location: "//example.com/login",
+			code:     http.StatusFound,
+			expected: "/login",
+		},
+		{
+			name:     "already relative",
+			location: "/login",
+			code:     http.StatusFound,
+			expected: "/login",
+		},
+		{
+			name:     "path relative",
+			location: "login",
+			code:     http.StatusFound,
+			expected: "login",
+		},
+		{
+			name:     "not a redirect",
+			location: "http://example.com/things/1",
+			code:     http.StatusCreated,
+			expected: "http://example.com/things/1",
+		},
+		{
+			name:     "base with absolute same host",
+			opts:     []RelativeRedirectsOption{WithRedirectBase("/app/")},
+			location: "http://example.com/login",
+			code:     http.StatusFound,
+			expected: "/app/login",
+		},
+		{
+			name:     "base with already relative",
+			opts:     []RelativeRedirectsOption{WithRedirectBase("/app")},
+			location: "/login",
+			code:     http.StatusFound,
+			expected: "/app/login",
+		},
+		{
+			name:     "base with absolute cross host",
+			opts:     []RelativeRedirectsOption{WithRedirectBase("/app")},
+			location: "https://other.example.com/login",
+			code:     http.StatusFound,
+			expected: "https://other.example.com/login",
+		},
+		{
+			name:     "base with path relative",
+			opts:     []RelativeRedirectsOption{WithRedirectBase("/app")},
+			location: "login",
+			code:     http.StatusFound,
+			expected: "login",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RelativeRedirects(tt.opts...)(redirectHandler(tt.location, tt.code))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+
+			assert.Equal(t, tt.code, rr.Code)
+			assert.Equal(t, tt.expected, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRelativeRedirects_HTTPRedirect(t *testing.T) {
+	handler := RelativeRedirects()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/login", http.StatusFound)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://example.com/account", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/login", rr.Header().Get("Location"))
+}
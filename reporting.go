@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ReportingEndpoints is a middleware that adds a Reporting-Endpoints header to
+// responses, defining named endpoints that browsers can send reports to. The
+// endpoints map contains endpoint names and their URLs.
+//
+// Endpoints can be referenced by name from other headers, such as the
+// `report-to` directive of a Content-Security-Policy header. As with Headers,
+// the header is added as late as possible.
+func ReportingEndpoints(endpoints map[string]string) func(http.Handler) http.Handler {
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = fmt.Sprintf(`%s="%s"`, name, endpoints[name])
+	}
+
+	return Headers(WithHeader("Reporting-Endpoints", strings.Join(values, ", ")))
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportingEndpoints(t *testing.T) {
+	handler := ReportingEndpoints(map[string]string{
+		"default":   "https://example.com/reports",
+		"csp-group": "https://example.com/csp",
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, `csp-group="https://example.com/csp", default="https://example.com/reports"`, rr.Header().Get("Reporting-Endpoints"))
+}
+
+func TestReportingEndpoints_CSPReference(t *testing.T) {
+	handler := Chain(WithMiddleware(
+		Headers(WithHeader("Content-Security-Policy", "default-src 'self'; report-to csp-group")),
+		ReportingEndpoints(map[string]string{"csp-group": "https://example.com/csp"}),
+	))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "default-src 'self'; report-to csp-group", rr.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, `csp-group="https://example.com/csp"`, rr.Header().Get("Reporting-Endpoints"))
+}
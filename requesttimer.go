@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type requestStartKey struct{}
+
+// RequestTimer is a middleware that records the time each request started, so
+// that handlers can find out how long they have been running using Elapsed.
+func RequestTimer() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestStartKey{}, time.Now())))
+		})
+	}
+}
+
+// Elapsed returns the time since the RequestTimer middleware started handling
+// the request, measured using the monotonic clock. Returns 0 if the middleware
+// was not used.
+func Elapsed(r *http.Request) time.Duration {
+	if start, ok := r.Context().Value(requestStartKey{}).(time.Time); ok {
+		return time.Since(start)
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimer_Elapsed(t *testing.T) {
+	var first, second time.Duration
+	handler := RequestTimer()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first = Elapsed(r)
+		time.Sleep(10 * time.Millisecond)
+		second = Elapsed(r)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.Greater(t, first, time.Duration(0))
+	assert.GreaterOrEqual(t, second-first, 10*time.Millisecond)
+	assert.Less(t, second, time.Second)
+}
+
+func TestRequestTimer_StartedBeforeInnerMiddleware(t *testing.T) {
+	var elapsed time.Duration
+	slow := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := RequestTimer()(slow(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elapsed = Elapsed(r)
+	})))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.GreaterOrEqual(t, elapsed, 10*time.Millisecond)
+}
+
+func TestElapsed_NoMiddleware(t *testing.T) {
+	assert.Equal(t, time.Duration(0), Elapsed(httptest.NewRequest("GET", "/test", nil)))
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// RewriteRule is a rule used by RewritePath to rewrite request paths.
+type RewriteRule struct {
+	// Pattern is the regular expression that paths must match for the rule to
+	// apply.
+	Pattern *regexp.Regexp
+	// Replacement replaces the part of the path matched by Pattern. It may refer
+	// to capture groups using the syntax accepted by regexp.Regexp.Expand (e.g.
+	// "$1" or "${name}").
+	Replacement string
+}
+
+// RewritePath is a middleware that rewrites the path of requests according to
+// the given rules, without redirecting the client. Rules are evaluated in
+// order, and only the first matching rule is applied. The rule replaces the
+// leftmost match of its pattern once; any other matches are left alone.
+//
+// Rules match against the unescaped request path. If the request used a
+// non-standard escaping (such as "%2F" within a segment), it is kept when the
+// rule produces the same result against the escaped path. The query string is
+// left unchanged.
+//
+// RewritePath panics if any rule does not have a Pattern.
+func RewritePath(rules ...RewriteRule) func(http.Handler) http.Handler {
+	for i := range rules {
+		if rules[i].Pattern == nil {
+			panic("middleware: rewrite rule has no pattern")
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := range rules {
+				path, ok := rules[i].rewrite(r.URL.Path)
+				if !ok {
+					continue
+				}
+
+				rawPath := ""
+				if r.URL.RawPath != "" {
+					if escaped, ok := rules[i].rewrite(r.URL.RawPath); ok {
+						if unescaped, err := url.PathUnescape(escaped); err == nil && unescaped == path {
+							rawPath = escaped
+						}
+					}
+				}
+
+				r.URL.Path = path
+				r.URL.RawPath = rawPath
+				break
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rewrite replaces the leftmost match of the rule's pattern in the path, if
+// there is one.
+func (rule RewriteRule) rewrite(path string) (string, bool) {
+	match := rule.Pattern.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", false
+	}
+
+	replacement := rule.Pattern.ExpandString(nil, rule.Replacement, path, match)
+	return path[:match[0]] + string(replacement) + path[match[1]:], true
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		expectedPath  string
+		expectedQuery string
+	}{
+		{"capture group", "/old/foo/bar", "/new/foo/bar", ""},
+		{"named group", "/users/123/profile", "/profile/123", ""},
+		{"query string preserved", "/old/foo?a=1&b=2", "/new/foo", "a=1&b=2"},
+		{"no match", "/other/foo", "/other/foo", ""},
+		{"first rule wins", "/old/users/1/profile", "/new/users/1/profile", ""},
+		{"unanchored match", "/a/legacy/b", "/a/current/b", ""},
+		{"only first match replaced", "/legacy/legacy", "/current/legacy", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, query string
+			handler := RewritePath(
+				RewriteRule{Pattern: regexp.MustCompile(`^/old/(.*)$`), Replacement: "/new/$1"},
+				RewriteRule{Pattern: regexp.MustCompile(`^/users/(?P<id>\d+)/profile$`), Replacement: "/profile/${id}"},
+				RewriteRule{Pattern: regexp.MustCompile(`/legacy`), Replacement: "/current"},
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				query = r.URL.RawQuery
+			}))
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, tt.expectedQuery, query)
+		})
+	}
+}
+
+func TestRewritePath_EscapedPath(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		expectedPath    string
+		expectedEscaped string
+	}{
+		{"escaping preserved", "/old/a%2Fb", "/new/a/b", "/new/a%2Fb"},
+		{"standard escaping", "/old/a%20b", "/new/a b", "/new/a%20b"},
+		{"escaped path doesn't match", "/ol%64/a%2Fb", "/new/a/b", "/new/a/b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, escaped string
+			handler := RewritePath(
+				RewriteRule{Pattern: regexp.MustCompile(`^/old/(.*)$`), Replacement: "/new/$1"},
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				escaped = r.URL.EscapedPath()
+			}))
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, tt.expectedEscaped, escaped)
+		})
+	}
+}
+
+func TestRewritePath_MissingPattern(t *testing.T) {
+	assert.Panics(t, func() {
+		RewritePath(RewriteRule{Replacement: "/new"})
+	})
+}
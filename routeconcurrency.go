@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type routeConcurrencyConfig struct {
+	wait time.Duration
+}
+
+type RouteConcurrencyOption func(*routeConcurrencyConfig)
+
+// WithRouteConcurrencyWait sets how long a request will wait for a route's
+// budget to become available before being rejected. Defaults to 0, meaning
+// requests are rejected immediately if the route is at its limit.
+func WithRouteConcurrencyWait(wait time.Duration) RouteConcurrencyOption {
+	return func(config *routeConcurrencyConfig) {
+		config.wait = wait
+	}
+}
+
+type routeBudget struct {
+	prefix string
+	slots  chan struct{}
+}
+
+// RouteConcurrency is a middleware that limits the number of requests being
+// handled concurrently for each route. Routes are identified by path prefix,
+// and each has its own independent budget; for example:
+//
+//	RouteConcurrency(map[string]int{"/report": 2, "/api/": 50})
+//
+// If a request matches multiple prefixes, the longest one is used. Requests
+// that don't match any prefix, or that match a prefix with a limit of zero or
+// less, are not limited.
+//
+// Requests that arrive when their route's budget is exhausted are responded
+// to with a 503 Service Unavailable response, without calling the next
+// handler.
+func RouteConcurrency(limits map[string]int, opts ...RouteConcurrencyOption) func(http.Handler) http.Handler {
+	config := &routeConcurrencyConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	budgets := make([]routeBudget, 0, len(limits))
+	for prefix, limit := range limits {
+		budget := routeBudget{prefix: prefix}
+		if limit > 0 {
+			budget.slots = make(chan struct{}, limit)
+		}
+		budgets = append(budgets, budget)
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		return len(budgets[i].prefix) > len(budgets[j].prefix)
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var slots chan struct{}
+			for i := range budgets {
+				if strings.HasPrefix(r.URL.Path, budgets[i].prefix) {
+					slots = budgets[i].slots
+					break
+				}
+			}
+
+			if slots == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !acquireRouteSlot(r, slots, config.wait) {
+				http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquireRouteSlot reserves a slot, waiting up to the given duration for one
+// to become available. Returns false if no slot could be acquired, or the
+// request was cancelled while waiting.
+func acquireRouteSlot(r *http.Request, slots chan struct{}, wait time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+		if wait <= 0 {
+			return false
+		}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
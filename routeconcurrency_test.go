@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingRouteHandler returns a handler that blocks requests with the
+// "block" query parameter until release is closed, signalling on started
+// once each has begun.
+func blockingRouteHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("block") != "" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func routeRequest(handler http.Handler, target string) int {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr.Code
+}
+
+func TestRouteConcurrency_IndependentRoutes(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := RouteConcurrency(map[string]int{
+		"/report": 2,
+		"/api/":   1,
+	})(blockingRouteHandler(started, release))
+
+	wg := &sync.WaitGroup{}
+	codes := make(chan int, 3)
+	for _, target := range []string{"/report?block=1", "/report?block=1", "/api/things?block=1"} {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			codes <- routeRequest(handler, target)
+		}(target)
+		<-started
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, routeRequest(handler, "/report"))
+	assert.Equal(t, http.StatusServiceUnavailable, routeRequest(handler, "/api/other"))
+	assert.Equal(t, http.StatusOK, routeRequest(handler, "/ping"))
+
+	close(release)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+
+	assert.Equal(t, http.StatusOK, routeRequest(handler, "/report"))
+	assert.Equal(t, http.StatusOK, routeRequest(handler, "/api/other"))
+}
+
+func TestRouteConcurrency_LongestPrefix(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := RouteConcurrency(map[string]int{
+		"/":       1,
+		"/health": 0,
+	})(blockingRouteHandler(started, release))
+
+	done := make(chan int)
+	go func() {
+		done <- routeRequest(handler, "/page?block=1")
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusServiceUnavailable, routeRequest(handler, "/other"))
+	assert.Equal(t, http.StatusOK, routeRequest(handler, "/health"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-done)
+}
+
+func TestRouteConcurrency_Wait(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := RouteConcurrency(
+		map[string]int{"/report": 1},
+		WithRouteConcurrencyWait(time.Minute),
+	)(blockingRouteHandler(started, release))
+
+	first := make(chan int)
+	go func() {
+		first <- routeRequest(handler, "/report?block=1")
+	}()
+	<-started
+
+	second := make(chan int)
+	go func() {
+		second <- routeRequest(handler, "/report")
+	}()
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+	assert.Equal(t, http.StatusOK, <-second)
+}
+
+func TestRouteConcurrency_WaitTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := RouteConcurrency(
+		map[string]int{"/report": 1},
+		WithRouteConcurrencyWait(10*time.Millisecond),
+	)(blockingRouteHandler(started, release))
+
+	first := make(chan int)
+	go func() {
+		first <- routeRequest(handler, "/report?block=1")
+	}()
+	<-started
+
+	assert.Equal(t, http.StatusServiceUnavailable, routeRequest(handler, "/report"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first)
+}
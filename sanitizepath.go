@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type sanitizePathConfig struct {
+	statusCode int
+}
+
+type SanitizePathOption func(*sanitizePathConfig)
+
+// WithSanitizePathStatus sets the status code used to respond to requests with
+// dangerous paths. Defaults to 400 Bad Request.
+func WithSanitizePathStatus(code int) SanitizePathOption {
+	return func(config *sanitizePathConfig) {
+		config.statusCode = code
+	}
+}
+
+// SanitizePath is a middleware that rejects requests with paths commonly used
+// in path traversal and request smuggling attacks. Requests are rejected if
+// their decoded path:
+//
+//   - contains a null byte;
+//   - contains a ".." segment that would escape the root (treating both "/"
+//     and "\" as separators); or
+//   - still contains an encoded ".", "/", "\" or null byte, indicating that it
+//     was encoded multiple times.
+//
+// Redundant percent-encodings of unreserved characters (e.g. "%41" for "A")
+// are also normalized. Other encodings, such as "%2F" within a filename, are
+// preserved.
+//
+// Rejected requests are responded to with a 400 response with no body, or the
+// status configured with WithSanitizePathStatus. Chain this middleware with
+// ErrorHandler to customise this.
+func SanitizePath(opts ...SanitizePathOption) func(http.Handler) http.Handler {
+	config := &sanitizePathConfig{
+		statusCode: http.StatusBadRequest,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !safePath(r.URL.Path) {
+				w.WriteHeader(config.statusCode)
+				return
+			}
+
+			if r.URL.RawPath != "" {
+				r.URL.RawPath = normalizeEscapes(r.URL.RawPath)
+				if r.URL.RawPath == (&url.URL{Path: r.URL.Path}).EscapedPath() {
+					r.URL.RawPath = ""
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func safePath(path string) bool {
+	if strings.ContainsRune(path, 0) {
+		return false
+	}
+
+	lower := strings.ToLower(path)
+	for _, encoded := range []string{"%2e", "%2f", "%5c", "%00"} {
+		if strings.Contains(lower, encoded) {
+			return false
+		}
+	}
+
+	depth := 0
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, segment := range segments {
+		switch segment {
+		case ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return false
+			}
+		default:
+			depth++
+		}
+	}
+	return true
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters, and
+// upper-cases the hex digits of any other percent-encodings.
+func normalizeEscapes(raw string) string {
+	var result strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '%' || i+2 >= len(raw) {
+			result.WriteByte(raw[i])
+			continue
+		}
+
+		decoded, err := url.PathUnescape(raw[i : i+3])
+		if err != nil {
+			result.WriteByte(raw[i])
+			continue
+		}
+
+		if c := decoded[0]; isUnreserved(c) {
+			result.WriteByte(c)
+		} else {
+			result.WriteString(strings.ToUpper(raw[i : i+3]))
+		}
+		i += 2
+	}
+	return result.String()
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizePath(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		expectedStatus  int
+		expectedPath    string
+		expectedEscaped string
+	}{
+		{"plain path", "/foo/bar", http.StatusOK, "/foo/bar", "/foo/bar"},
+		{"traversal within root", "/foo/../bar", http.StatusOK, "/foo/../bar", "/foo/../bar"},
+		{"encoded traversal", "/%2e%2e%2fetc/passwd", http.StatusBadRequest, "", ""},
+		{"encoded traversal within root", "/foo/%2e%2e/bar", http.StatusOK, "/foo/../bar", "/foo/../bar"},
+		{"plain traversal", "/../etc/passwd", http.StatusBadRequest, "", ""},
+		{"nested traversal", "/foo/../../etc/passwd", http.StatusBadRequest, "", ""},
+		{"backslash traversal", "/foo/..%5c..%5cetc", http.StatusBadRequest, "", ""},
+		{"double encoded traversal", "/%252e%252e/etc/passwd", http.StatusBadRequest, "", ""},
+		{"null byte", "/foo%00.txt", http.StatusBadRequest, "", ""},
+		{"double encoded null byte", "/foo%2500.txt", http.StatusBadRequest, "", ""},
+		{"encoded space in filename", "/files/my%20file.txt", http.StatusOK, "/files/my file.txt", "/files/my%20file.txt"},
+		{"encoded slash in filename", "/files/a%2fb.txt", http.StatusOK, "/files/a/b.txt", "/files/a%2Fb.txt"},
+		{"percent in filename", "/files/100%25.txt", http.StatusOK, "/files/100%.txt", "/files/100%25.txt"},
+		{"redundant encoding", "/%66oo/b%61r", http.StatusOK, "/foo/bar", "/foo/bar"},
+		{"unicode filename", "/files/%C3%A9t%C3%A9.txt", http.StatusOK, "/files/été.txt", "/files/%C3%A9t%C3%A9.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, escaped string
+			handler := SanitizePath()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				escaped = r.URL.EscapedPath()
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, tt.expectedEscaped, escaped)
+		})
+	}
+}
+
+func TestSanitizePath_CustomStatus(t *testing.T) {
+	handler := SanitizePath(WithSanitizePathStatus(http.StatusNotFound))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/%2e%2e/etc/passwd", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
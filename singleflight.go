@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+)
+
+type singleFlightConfig struct {
+	key     func(*http.Request) string
+	maxSize int
+}
+
+type SingleFlightOption func(*singleFlightConfig)
+
+// WithSingleFlightKey sets a function that determines which requests are
+// considered identical. If the function returns an empty string, the request
+// is never coalesced. By default, requests are identical if they have the
+// same method, host and request URI, and requests with an Authorization or
+// Cookie header are never coalesced, as their responses are likely to be
+// specific to a user.
+func WithSingleFlightKey(key func(*http.Request) string) SingleFlightOption {
+	return func(config *singleFlightConfig) {
+		config.key = key
+	}
+}
+
+// WithSingleFlightMaxSize sets the maximum size of response body that will be
+// buffered and shared with duplicate requests. Defaults to 1MiB.
+func WithSingleFlightMaxSize(size int) SingleFlightOption {
+	return func(config *singleFlightConfig) {
+		config.maxSize = size
+	}
+}
+
+// SingleFlight is a middleware that coalesces identical GET and HEAD requests
+// that are in flight at the same time.
+//
+// The first request is passed to the next handler, and its response is
+// buffered. Any identical requests that arrive before it completes wait for
+// it, and are then sent a copy of the same response without invoking the next
+// handler.
+//
+// If the response is larger than the maximum size, or the handler flushes it,
+// the response is streamed to the first client instead, and the waiting
+// requests are passed to the next handler as normal. Set-Cookie headers are
+// only sent to the first client, never to the waiting requests.
+func SingleFlight(opts ...SingleFlightOption) func(http.Handler) http.Handler {
+	config := &singleFlightConfig{
+		key:     defaultSingleFlightKey,
+		maxSize: 1024 * 1024,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var mutex sync.Mutex
+	calls := make(map[string]*singleFlightCall)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := config.key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mutex.Lock()
+			if call, ok := calls[key]; ok {
+				mutex.Unlock()
+
+				select {
+				case <-call.done:
+				case <-r.Context().Done():
+					return
+				}
+
+				if call.response != nil {
+					replayIdempotentResponse(w, call.response)
+				} else {
+					next.ServeHTTP(w, r)
+				}
+				return
+			}
+
+			call := &singleFlightCall{done: make(chan struct{})}
+			calls[key] = call
+			mutex.Unlock()
+
+			wrapped := &singleFlightWrapper{
+				ResponseWriter: w,
+				maxSize:        config.maxSize,
+				status:         http.StatusOK,
+			}
+
+			completed := false
+			defer func() {
+				mutex.Lock()
+				delete(calls, key)
+				mutex.Unlock()
+
+				if completed && !wrapped.streaming {
+					call.response = wrapped.response()
+				}
+				close(call.done)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+			wrapped.finish()
+			completed = true
+		})
+	}
+}
+
+func defaultSingleFlightKey(r *http.Request) string {
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+		return ""
+	}
+	return r.Method + " " + r.Host + r.URL.RequestURI()
+}
+
+type singleFlightCall struct {
+	done     chan struct{}
+	response *IdempotentResponse
+}
+
+// singleFlightWrapper buffers a response until it completes, or switches to
+// streaming it if it becomes too large or is flushed.
+type singleFlightWrapper struct {
+	http.ResponseWriter
+	maxSize   int
+	status    int
+	header    http.Header
+	body      bytes.Buffer
+	headers   bool
+	streaming bool
+}
+
+func (s *singleFlightWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		s.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if s.headers {
+		return
+	}
+	s.headers = true
+	s.status = code
+	s.header = s.ResponseWriter.Header().Clone()
+}
+
+func (s *singleFlightWrapper) Write(b []byte) (int, error) {
+	if !s.headers {
+		s.WriteHeader(http.StatusOK)
+	}
+
+	if s.streaming {
+		return s.ResponseWriter.Write(b)
+	}
+
+	if s.body.Len()+len(b) > s.maxSize {
+		if err := s.stream(); err != nil {
+			return 0, err
+		}
+		return s.ResponseWriter.Write(b)
+	}
+
+	return s.body.Write(b)
+}
+
+// stream sends the buffered response to the client, and stops any further
+// buffering.
+func (s *singleFlightWrapper) stream() error {
+	if s.streaming {
+		return nil
+	}
+	s.streaming = true
+
+	if !s.headers {
+		s.WriteHeader(http.StatusOK)
+	}
+	s.ResponseWriter.WriteHeader(s.status)
+	_, err := s.ResponseWriter.Write(s.body.Bytes())
+	s.body.Reset()
+	return err
+}
+
+// finish sends the buffered response to the client once the handler has
+// completed, if it hasn't already been streamed.
+func (s *singleFlightWrapper) finish() {
+	if !s.streaming {
+		s.ResponseWriter.WriteHeader(s.status)
+		s.ResponseWriter.Write(s.body.Bytes())
+	}
+}
+
+func (s *singleFlightWrapper) response() *IdempotentResponse {
+	header := s.header
+	if !s.headers {
+		header = s.ResponseWriter.Header().Clone()
+	}
+	// Cookies are for the first client only, and mustn't leak to others
+	header.Del("Set-Cookie")
+
+	return &IdempotentResponse{
+		StatusCode: s.status,
+		Header:     header,
+		Body:       s.body.Bytes(),
+	}
+}
+
+func (s *singleFlightWrapper) Flush() {
+	s.stream()
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveConcurrently(handler http.Handler, method string, count int) []*httptest.ResponseRecorder {
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, count)
+	for i := range recorders {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rr *httptest.ResponseRecorder) {
+			defer wg.Done()
+			handler.ServeHTTP(rr, httptest.NewRequest(method, "/test", nil))
+		}(recorders[i])
+	}
+	wg.Wait()
+	return recorders
+}
+
+func TestSingleFlight_ConcurrentDuplicates(t *testing.T) {
+	var calls int32
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("X-Custom", "value")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("expensive result"))
+	}))
+
+	recorders := serveConcurrently(handler, "GET", 5)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, rr := range recorders {
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, "value", rr.Header().Get("X-Custom"))
+		assert.Equal(t, "expensive result", rr.Body.String())
+	}
+}
+
+func TestSingleFlight_SequentialRequests(t *testing.T) {
+	calls := 0
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("result"))
+	}))
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+		assert.Equal(t, "result", rr.Body.String())
+	}
+
+	assert.Equal(t, 3, calls)
+}
+
+func TestSingleFlight_UnsafeMethods(t *testing.T) {
+	var calls int32
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+
+	serveConcurrently(handler, "POST", 3)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestSingleFlight_DifferentKeys(t *testing.T) {
+	var calls int32
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(r.URL.Path))
+	}))
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/b", "/c"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			assert.Equal(t, path, rr.Body.String())
+		}(path)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestSingleFlight_CustomKey(t *testing.T) {
+	var calls int32
+	handler := SingleFlight(WithSingleFlightKey(func(r *http.Request) string {
+		return "everything"
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/a", "/b", "/c"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		}(path)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestSingleFlight_OverMaxSize(t *testing.T) {
+	var calls int32
+	content := strings.Repeat("a", 100)
+	handler := SingleFlight(WithSingleFlightMaxSize(50))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(content[:40]))
+		w.Write([]byte(content[40:]))
+	}))
+
+	recorders := serveConcurrently(handler, "GET", 3)
+
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(1))
+	for _, rr := range recorders {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, content, rr.Body.String())
+	}
+}
+
+func TestSingleFlight_CredentialedRequests(t *testing.T) {
+	var calls int32
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		user := r.Header.Get("Authorization") + r.Header.Get("Cookie")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: user})
+		w.Write([]byte("private data for " + user))
+	}))
+
+	users := []struct {
+		header string
+		value  string
+	}{
+		{"Authorization", "alice"},
+		{"Authorization", "bob"},
+		{"Cookie", "carol"},
+		{"Cookie", "dave"},
+	}
+
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, len(users))
+	for i := range users {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set(users[i].header, users[i].value)
+			handler.ServeHTTP(recorders[i], req)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(len(users)), atomic.LoadInt32(&calls))
+	for i := range users {
+		assert.Equal(t, "private data for "+users[i].value, recorders[i].Body.String())
+		assert.Equal(t, "session="+users[i].value, recorders[i].Header().Get("Set-Cookie"))
+	}
+}
+
+func TestSingleFlight_SetCookieNotReplayed(t *testing.T) {
+	var calls int32
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123"})
+		w.Write([]byte("shared result"))
+	}))
+
+	recorders := serveConcurrently(handler, "GET", 5)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	cookies := 0
+	for _, rr := range recorders {
+		assert.Equal(t, "shared result", rr.Body.String())
+		if rr.Header().Get("Set-Cookie") != "" {
+			cookies++
+		}
+	}
+	assert.Equal(t, 1, cookies)
+}
+
+func TestSingleFlight_Informational(t *testing.T) {
+	handler := SingleFlight()(earlyHintsHandler)
+
+	informational, res, body := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html", res.Header.Get("Content-Type"))
+	assert.Equal(t, "test content", body)
+}
+
+func TestSingleFlight_InformationalNotReplayed(t *testing.T) {
+	handler := SingleFlight()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		earlyHintsHandler.ServeHTTP(w, r)
+	}))
+
+	recorders := serveConcurrently(handler, "GET", 3)
+
+	// The recorder for the first request latches the 103 (see
+	// serveWithInformational), but the waiting requests must get the 200
+	replayed := 0
+	for _, rr := range recorders {
+		assert.Equal(t, "test content", rr.Body.String())
+		if rr.Code == http.StatusOK {
+			replayed++
+		}
+	}
+	assert.Equal(t, 2, replayed)
+}
@@ -6,7 +6,9 @@ import (
 )
 
 type redirectTrailingSlashesConfig struct {
-	redirectCode int
+	redirectCode   int
+	skipExtensions bool
+	noStore        bool
 }
 
 type RedirectTrailingSlashesOption func(*redirectTrailingSlashesConfig)
@@ -19,6 +21,26 @@ func WithRedirectCode(code int) RedirectTrailingSlashesOption {
 	}
 }
 
+// WithRedirectSkipExtensions sets whether paths that look like files (i.e.,
+// the last segment contains a `.`, such as `/foo.js`) should be left alone
+// instead of being redirected. Defaults to false.
+func WithRedirectSkipExtensions(skip bool) RedirectTrailingSlashesOption {
+	return func(config *redirectTrailingSlashesConfig) {
+		config.skipExtensions = skip
+	}
+}
+
+// WithRedirectNoStore sets whether redirects should be sent with a
+// `Cache-Control: no-store` header, so that browsers request the original URL
+// again each time rather than following a cached redirect. This is useful if
+// the redirect may change, as permanent redirects are otherwise cached
+// indefinitely. Defaults to false.
+func WithRedirectNoStore(noStore bool) RedirectTrailingSlashesOption {
+	return func(config *redirectTrailingSlashesConfig) {
+		config.noStore = noStore
+	}
+}
+
 // StripTrailingSlashes is a middleware that removes trailing slashes from
 // URLs.
 func StripTrailingSlashes() func(http.Handler) http.Handler {
@@ -46,9 +68,12 @@ func RedirectTrailingSlashes(opts ...RedirectTrailingSlashesOption) func(http.Ha
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.URL.Path != "/" && !strings.HasSuffix(r.URL.Path, "/") {
+			if r.URL.Path != "/" && !strings.HasSuffix(r.URL.Path, "/") && !(config.skipExtensions && looksLikeFile(r.URL.Path)) {
 				newURL := *r.URL
 				newURL.Path = r.URL.Path + "/"
+				if config.noStore {
+					w.Header().Set("Cache-Control", "no-store")
+				}
 				http.Redirect(w, r, newURL.String(), config.redirectCode)
 				return
 			}
@@ -56,3 +81,9 @@ func RedirectTrailingSlashes(opts ...RedirectTrailingSlashesOption) func(http.Ha
 		})
 	}
 }
+
+// looksLikeFile determines whether the last segment of the path contains a
+// file extension.
+func looksLikeFile(path string) bool {
+	return strings.Contains(path[strings.LastIndex(path, "/")+1:], ".")
+}
@@ -143,3 +143,81 @@ func TestRedirectTrailingSlashes_CustomRedirectCode(t *testing.T) {
 	assert.Equal(t, http.StatusMovedPermanently, rr.Code)
 	assert.Equal(t, "/test/", rr.Header().Get("Location"))
 }
+
+func TestRedirectTrailingSlashes_NoStore(t *testing.T) {
+	handler := RedirectTrailingSlashes(WithRedirectNoStore(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+	assert.Equal(t, "/test/", rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test/", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Cache-Control"))
+}
+
+func TestRedirectTrailingSlashes_NoStoreDisabledByDefault(t *testing.T) {
+	handler := RedirectTrailingSlashes()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+	assert.Empty(t, rr.Header().Get("Cache-Control"))
+}
+
+func TestRedirectTrailingSlashes_SkipExtensions(t *testing.T) {
+	tests := []struct {
+		path     string
+		redirect bool
+	}{
+		{"/foo", true},
+		{"/foo.js", false},
+		{"/foo.bar/baz", true},
+		{"/static/app.min.css", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			handler := RedirectTrailingSlashes(WithRedirectSkipExtensions(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("test content"))
+			}))
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if tt.redirect {
+				assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+				assert.Equal(t, tt.path+"/", rr.Header().Get("Location"))
+			} else {
+				assert.Equal(t, http.StatusOK, rr.Code)
+				assert.Equal(t, "test content", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedirectTrailingSlashes_ExtensionsRedirectedByDefault(t *testing.T) {
+	handler := RedirectTrailingSlashes()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/foo.js", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+	assert.Equal(t, "/foo.js/", rr.Header().Get("Location"))
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type slidingWindowConfig struct {
+	key   func(*http.Request) string
+	clock func() time.Time
+}
+
+type SlidingWindowOption func(*slidingWindowConfig)
+
+// WithSlidingWindowKey sets a function that determines which client a request
+// belongs to. Each client has a separate limit. By default, requests are
+// grouped by the IP address in RemoteAddr.
+func WithSlidingWindowKey(key func(*http.Request) string) SlidingWindowOption {
+	return func(config *slidingWindowConfig) {
+		config.key = key
+	}
+}
+
+// SlidingWindowLimit is a middleware that limits each client to the given
+// number of requests within a trailing window of time, e.g. 100 requests per
+// minute. If RealAddress is used it should be applied before this middleware,
+// so that clients are identified correctly.
+//
+// All responses have an X-RateLimit-Remaining header containing the number of
+// requests the client can still make in the current window, and an
+// X-RateLimit-Reset header containing the number of seconds until the oldest
+// request leaves the window. Requests that exceed the limit are responded to
+// with a 429 Too Many Requests response with a Retry-After header, without
+// calling the next handler. Rejected requests don't count towards the limit.
+func SlidingWindowLimit(limit int, window time.Duration, opts ...SlidingWindowOption) func(http.Handler) http.Handler {
+	config := &slidingWindowConfig{
+		key:   defaultRateLimitKey,
+		clock: time.Now,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	limiter := &slidingWindowLimiter{
+		limit:     limit,
+		window:    window,
+		requests:  make(map[string][]time.Time),
+		lastSweep: config.clock(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remaining, reset, ok := limiter.take(config.key(r), config.clock())
+
+			resetSeconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", resetSeconds)
+
+			if !ok {
+				w.Header().Set("Retry-After", resetSeconds)
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type slidingWindowLimiter struct {
+	limit     int
+	window    time.Duration
+	mutex     sync.Mutex
+	requests  map[string][]time.Time
+	lastSweep time.Time
+}
+
+// take attempts to record a request for the given key. Returns the number of
+// requests remaining in the window, the time until the oldest request leaves
+// the window, and whether the request is allowed.
+func (l *slidingWindowLimiter) take(key string, now time.Time) (int, time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep(now)
+
+	requests := l.prune(l.requests[key], now)
+	allowed := len(requests) < l.limit
+	if allowed {
+		requests = append(requests, now)
+	}
+
+	if len(requests) == 0 {
+		delete(l.requests, key)
+		return l.limit, 0, allowed
+	}
+
+	l.requests[key] = requests
+	return l.limit - len(requests), requests[0].Add(l.window).Sub(now), allowed
+}
+
+// blocked determines whether the number of requests recorded for the given key
+// has reached the limit. If so, also returns the time until it will drop back
+// below the limit.
+func (l *slidingWindowLimiter) blocked(key string, now time.Time) (time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.sweep(now)
+
+	requests := l.prune(l.requests[key], now)
+	if len(requests) < l.limit {
+		return 0, false
+	}
+
+	l.requests[key] = requests
+	return requests[len(requests)-l.limit].Add(l.window).Sub(now), true
+}
+
+// add records a request for the given key, regardless of the limit.
+func (l *slidingWindowLimiter) add(key string, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.requests[key] = append(l.prune(l.requests[key], now), now)
+}
+
+// prune removes any requests that are outside the window.
+func (l *slidingWindowLimiter) prune(requests []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(requests) && !requests[i].After(cutoff) {
+		i++
+	}
+	return requests[i:]
+}
+
+// sweep removes any keys that have no requests in the window, at most once
+// per window.
+func (l *slidingWindowLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for k := range l.requests {
+		if len(l.prune(l.requests[k], now)) == 0 {
+			delete(l.requests, k)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withSlidingWindowClock(clock func() time.Time) SlidingWindowOption {
+	return func(config *slidingWindowConfig) {
+		config.clock = clock
+	}
+}
+
+func TestSlidingWindowLimit_WindowBoundary(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := SlidingWindowLimit(3, time.Minute, withSlidingWindowClock(func() time.Time {
+		return now
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Requests at 0s, 20s and 40s use up the limit
+	for _, remaining := range []string{"2", "1", "0"} {
+		rr := serve()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, remaining, rr.Header().Get("X-RateLimit-Remaining"))
+		now = now.Add(20 * time.Second)
+	}
+
+	// At 59s, the first request is still in the window
+	now = now.Add(-time.Second)
+	rr := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Reset"))
+
+	// At 60s, the first request has left the window
+	now = now.Add(time.Second)
+	rr = serve()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "20", rr.Header().Get("X-RateLimit-Reset"))
+
+	// Only one slot is freed
+	rr = serve()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "20", rr.Header().Get("Retry-After"))
+
+	// After a whole window with no requests, the full limit is available
+	now = now.Add(2 * time.Minute)
+	rr = serve()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", rr.Header().Get("X-RateLimit-Reset"))
+}
+
+func TestSlidingWindowLimit_SeparateClients(t *testing.T) {
+	handler := SlidingWindowLimit(1, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(addr string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.1:5678"))
+	assert.Equal(t, http.StatusOK, serve("192.0.2.2"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("192.0.2.2"))
+	assert.Equal(t, http.StatusOK, serve("2001:db8::1"))
+}
+
+func TestSlidingWindowLimit_CustomKey(t *testing.T) {
+	handler := SlidingWindowLimit(1, time.Minute, WithSlidingWindowKey(func(r *http.Request) string {
+		return r.Header.Get("X-API-Key")
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(key string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("a"))
+	assert.Equal(t, http.StatusOK, serve("b"))
+}
+
+func TestSlidingWindowLimit_EvictsIdleKeys(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := &slidingWindowLimiter{
+		limit:     5,
+		window:    time.Minute,
+		requests:  make(map[string][]time.Time),
+		lastSweep: now,
+	}
+
+	limiter.take("a", now)
+	limiter.take("b", now.Add(30*time.Second))
+	assert.Len(t, limiter.requests, 2)
+
+	limiter.take("c", now.Add(time.Minute+time.Second))
+	assert.Len(t, limiter.requests, 2)
+	assert.NotContains(t, limiter.requests, "a")
+}
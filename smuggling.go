@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RejectSmuggling is a middleware that rejects requests with ambiguous framing
+// that could be used for request smuggling, where a proxy and the server
+// disagree about where a request ends. Requests are rejected with a 400
+// response if they have:
+//
+//   - multiple Content-Length values that differ, or one that isn't a number
+//   - both a Content-Length and a Transfer-Encoding
+//
+// net/http's server already refuses or normalises most such requests before
+// they reach a handler, so this mainly provides defence in depth, e.g. for
+// requests that arrive via other transports or are constructed by other
+// middleware.
+func RejectSmuggling() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ambiguousFraming(r) {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ambiguousFraming determines whether the request's Content-Length and
+// Transfer-Encoding headers conflict.
+func ambiguousFraming(r *http.Request) bool {
+	length := ""
+	for _, value := range r.Header.Values("Content-Length") {
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" || strings.Trim(v, "0123456789") != "" {
+				return true
+			}
+			if length != "" && v != length {
+				return true
+			}
+			length = v
+		}
+	}
+
+	chunked := len(r.TransferEncoding) > 0 || len(r.Header.Values("Transfer-Encoding")) > 0
+	return length != "" && chunked
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectSmuggling(t *testing.T) {
+	tests := []struct {
+		name             string
+		contentLength    []string
+		transferEncoding []string
+		parsedEncoding   []string
+		expectedCode     int
+	}{
+		{"no framing headers", nil, nil, nil, http.StatusOK},
+		{"single content length", []string{"10"}, nil, nil, http.StatusOK},
+		{"chunked", nil, []string{"chunked"}, nil, http.StatusOK},
+		{"parsed chunked", nil, nil, []string{"chunked"}, http.StatusOK},
+		{"duplicate matching content lengths", []string{"10", "10"}, nil, nil, http.StatusOK},
+		{"conflicting content lengths", []string{"10", "20"}, nil, nil, http.StatusBadRequest},
+		{"conflicting content lengths in one header", []string{"10, 20"}, nil, nil, http.StatusBadRequest},
+		{"invalid content length", []string{"-10"}, nil, nil, http.StatusBadRequest},
+		{"empty content length", []string{""}, nil, nil, http.StatusBadRequest},
+		{"content length and transfer encoding", []string{"10"}, []string{"chunked"}, nil, http.StatusBadRequest},
+		{"content length and parsed transfer encoding", []string{"10"}, nil, []string{"chunked"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RejectSmuggling()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for _, v := range tt.contentLength {
+				req.Header.Add("Content-Length", v)
+			}
+			for _, v := range tt.transferEncoding {
+				req.Header.Add("Transfer-Encoding", v)
+			}
+			req.TransferEncoding = tt.parsedEncoding
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+//go:build go1.21
+
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type structuredLogConfig struct {
+	logger    *slog.Logger
+	levelFunc func(status int) slog.Level
+	since     func(time.Time) time.Duration
+}
+
+type StructuredLogOption func(*structuredLogConfig)
+
+// WithSlogLogger sets the logger used by StructuredLog. Defaults to
+// slog.Default().
+func WithSlogLogger(logger *slog.Logger) StructuredLogOption {
+	return func(config *structuredLogConfig) {
+		config.logger = logger
+	}
+}
+
+// WithSlogLevelFunc sets a function that StructuredLog uses to choose the
+// level each request is logged at, based on the response's status code.
+//
+// By default, 5xx responses are logged at Error, 4xx responses at Warn, and
+// all other responses at Info.
+func WithSlogLevelFunc(levelFunc func(status int) slog.Level) StructuredLogOption {
+	return func(config *structuredLogConfig) {
+		config.levelFunc = levelFunc
+	}
+}
+
+// StructuredLog is a middleware that logs details of each request using a
+// log/slog Logger.
+//
+// The request method, URL, protocol, remote address, referer and user agent
+// are logged, along with the response status, number of bytes written, and
+// duration of the request.
+func StructuredLog(opts ...StructuredLogOption) func(http.Handler) http.Handler {
+	config := &structuredLogConfig{
+		logger:    slog.Default(),
+		levelFunc: defaultSlogLevel,
+		since:     time.Since,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &statusRecorder{
+				ResponseWriter: w,
+			}
+
+			began := time.Now()
+			next.ServeHTTP(wrapped, r)
+			duration := config.since(began)
+
+			status := wrapped.status
+			if status == 0 {
+				// Nothing was written, so the server will send an empty 200 response
+				status = http.StatusOK
+			}
+
+			config.logger.LogAttrs(
+				r.Context(),
+				config.levelFunc(status),
+				"Request served",
+				slog.String("method", r.Method),
+				slog.String("url", r.URL.String()),
+				slog.String("proto", r.Proto),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.String("referer", r.Referer()),
+				slog.String("user_agent", r.UserAgent()),
+				slog.Int("status", status),
+				slog.Int("bytes", wrapped.written),
+				slog.Duration("duration", duration),
+			)
+		})
+	}
+}
+
+func defaultSlogLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,152 @@
+//go:build go1.21
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredLog_Attributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := StructuredLog(WithSlogLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Hello World!"))
+	}))
+
+	req := httptest.NewRequest("POST", "/test?a=b", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Header.Set("Referer", "http://www.example.com/")
+	req.Header.Set("User-Agent", "TestAgent/1.0")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/test?a=b", entry["url"])
+	assert.Equal(t, "HTTP/1.1", entry["proto"])
+	assert.Equal(t, "127.0.0.1:8080", entry["remote_addr"])
+	assert.Equal(t, "http://www.example.com/", entry["referer"])
+	assert.Equal(t, "TestAgent/1.0", entry["user_agent"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, float64(12), entry["bytes"])
+	assert.Contains(t, entry, "duration")
+}
+
+func TestStructuredLog_DefaultLevels(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected string
+	}{
+		{http.StatusOK, "INFO"},
+		{http.StatusNoContent, "INFO"},
+		{http.StatusMovedPermanently, "INFO"},
+		{http.StatusNotModified, "INFO"},
+		{http.StatusBadRequest, "WARN"},
+		{http.StatusNotFound, "WARN"},
+		{http.StatusInternalServerError, "ERROR"},
+		{http.StatusServiceUnavailable, "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			handler := StructuredLog(WithSlogLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			assert.Equal(t, tt.expected, entry["level"])
+		})
+	}
+}
+
+func TestStructuredLog_CustomLevelFunc(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	var receivedStatus int
+	handler := StructuredLog(
+		WithSlogLogger(logger),
+		WithSlogLevelFunc(func(status int) slog.Level {
+			receivedStatus = status
+			return slog.LevelDebug
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, http.StatusNotFound, receivedStatus)
+}
+
+func TestStructuredLog_NothingWritten(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := StructuredLog(WithSlogLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(http.StatusOK), entry["status"])
+}
+
+func TestStructuredLog_Duration(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	handler := StructuredLog(WithSlogLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.GreaterOrEqual(t, entry["duration"], float64(2*time.Millisecond))
+}
+
+func TestStructuredLog_DurationUsesSince(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	var began time.Time
+	before := time.Now()
+	handler := StructuredLog(WithSlogLogger(logger), func(config *structuredLogConfig) {
+		config.since = func(t time.Time) time.Duration {
+			began = t
+			return time.Minute
+		}
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(time.Minute), entry["duration"])
+	assert.False(t, began.Before(before))
+}
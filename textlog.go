@@ -2,8 +2,10 @@ package middleware
 
 import (
 	"fmt"
+	"math/rand"
 	"net"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
@@ -15,12 +17,29 @@ const (
 	TextLogFormatCommon TextLogFormat = iota
 	// TextLogFormatCombined is the "Combined Log Format" as used by Apache and Nginx
 	TextLogFormatCombined
+	// TextLogFormatJSON logs the same details as TextLogFormatCombined as a JSON object
+	TextLogFormatJSON
 )
 
 type textLogConfig struct {
-	sink   func(string)
-	format TextLogFormat
-	clock  func() time.Time
+	sink         func(string)
+	sinkErr      func(error)
+	format       TextLogFormat
+	formatFunc   func(*http.Request) TextLogFormat
+	clock        func() time.Time
+	since        func(time.Time) time.Duration
+	lifecycle    bool
+	timeFormat   string
+	utc          bool
+	countHeaders bool
+	sampleRate   float64
+	sampler      func() float64
+	respHeaders  []string
+	splitURL     bool
+	slowFunc     func(*http.Request) time.Duration
+	hostname     string
+	excludeUAs   []string
+	buffer       *TextLogBuffer
 }
 
 type TextLogOption func(*textLogConfig)
@@ -32,6 +51,48 @@ func WithTextLogSink(sink func(string)) TextLogOption {
 	}
 }
 
+// WithTextLogSinkErr specifies where logs should be written to by TextLog,
+// using a sink that can fail. Any errors returned by the sink are passed to
+// the handler set by WithTextLogSinkErrorHandler.
+func WithTextLogSinkErr(sink func(string) error) TextLogOption {
+	return func(config *textLogConfig) {
+		config.sink = func(s string) {
+			if err := sink(s); err != nil && config.sinkErr != nil {
+				config.sinkErr(err)
+			}
+		}
+	}
+}
+
+// WithTextLogSinkErrorHandler sets a function to be called when the sink set
+// by WithTextLogSinkErr fails to write a line, e.g. so that operators can be
+// alerted. By default, such errors are ignored.
+func WithTextLogSinkErrorHandler(handler func(error)) TextLogOption {
+	return func(config *textLogConfig) {
+		config.sinkErr = handler
+	}
+}
+
+// WithTextLogSinks specifies multiple sinks that logs should be written to by
+// TextLog. Each line is written to every sink in turn; if a sink panics, the
+// panic is recovered and the line is still written to the remaining sinks.
+func WithTextLogSinks(sinks ...func(string)) TextLogOption {
+	return func(config *textLogConfig) {
+		config.sink = func(s string) {
+			for i := range sinks {
+				writeTextLogSink(sinks[i], s)
+			}
+		}
+	}
+}
+
+func writeTextLogSink(sink func(string), s string) {
+	defer func() {
+		_ = recover()
+	}()
+	sink(s)
+}
+
 // WithTextLogFormat specifies the log format used by TextLog.
 func WithTextLogFormat(format TextLogFormat) TextLogOption {
 	return func(config *textLogConfig) {
@@ -39,6 +100,134 @@ func WithTextLogFormat(format TextLogFormat) TextLogOption {
 	}
 }
 
+// WithTextLogFormatFunc sets a function that chooses the log format used by
+// TextLog for each request, e.g. to log more detail for some paths than
+// others. Overrides WithTextLogFormat.
+func WithTextLogFormatFunc(format func(*http.Request) TextLogFormat) TextLogOption {
+	return func(config *textLogConfig) {
+		config.formatFunc = format
+	}
+}
+
+// WithTextLogTimeFormat sets the layout used to format timestamps, as accepted
+// by time.Time.Format. The timestamp is always surrounded by square brackets.
+// Defaults to the format used by Apache, "02/Jan/2006:15:04:05 -0700".
+func WithTextLogTimeFormat(layout string) TextLogOption {
+	return func(config *textLogConfig) {
+		config.timeFormat = layout
+	}
+}
+
+// WithTextLogUTC sets whether timestamps should be converted to UTC before
+// being formatted. By default, timestamps are in local time.
+func WithTextLogUTC(utc bool) TextLogOption {
+	return func(config *textLogConfig) {
+		config.utc = utc
+	}
+}
+
+// WithTextLogCountHeaders sets whether the number of bytes logged by TextLog
+// should include the response's status line and headers, rather than just the
+// body. The size of the headers is an approximation calculated from the
+// headers present once the request has completed, and may differ slightly
+// from what was actually sent.
+func WithTextLogCountHeaders(countHeaders bool) TextLogOption {
+	return func(config *textLogConfig) {
+		config.countHeaders = countHeaders
+	}
+}
+
+// WithTextLogSampleRate sets the fraction of requests that should be logged,
+// between 0 and 1. Requests that result in a server error (a status of 500 or
+// above) are always logged regardless of sampling. Defaults to 1, logging every
+// request.
+func WithTextLogSampleRate(rate float64) TextLogOption {
+	return func(config *textLogConfig) {
+		config.sampleRate = rate
+	}
+}
+
+// WithTextLogResponseHeaders adds the values of the given response headers to
+// each log line, as they were when the request completed. For the Common and
+// Combined formats, each value is appended to the line in quotes (before the
+// request ID, if any); for the JSON format, they are included in a
+// "response_headers" object.
+func WithTextLogResponseHeaders(names ...string) TextLogOption {
+	return func(config *textLogConfig) {
+		config.respHeaders = append(config.respHeaders, names...)
+	}
+}
+
+// WithTextLogSplitURL sets whether the JSON format should log the request's
+// path and query string as separate "path" and "query" fields, instead of a
+// single "url" field. This has no effect on the other formats.
+func WithTextLogSplitURL(split bool) TextLogOption {
+	return func(config *textLogConfig) {
+		config.splitURL = split
+	}
+}
+
+// WithTextLogSlowThreshold flags requests that take at least the given
+// duration to complete as slow. For the Common and Combined formats, the word
+// "slow" is appended to the line (before the request ID, if any); for the JSON
+// format, a "slow" field is included. Slow requests are always logged,
+// regardless of WithTextLogSampleRate.
+func WithTextLogSlowThreshold(threshold time.Duration) TextLogOption {
+	return WithTextLogSlowThresholdFunc(func(*http.Request) time.Duration {
+		return threshold
+	})
+}
+
+// WithTextLogSlowThresholdFunc is like WithTextLogSlowThreshold, but calls the
+// given function to determine the threshold for each request, allowing e.g.
+// different thresholds for different paths. If the function returns zero or a
+// negative duration, the request is never flagged as slow.
+func WithTextLogSlowThresholdFunc(threshold func(*http.Request) time.Duration) TextLogOption {
+	return func(config *textLogConfig) {
+		config.slowFunc = threshold
+	}
+}
+
+// WithTextLogHostname adds a "host" field containing the given hostname to
+// each line logged in the JSON format, to identify which server handled the
+// request when logs from multiple instances are aggregated. If the hostname is
+// empty, the value returned by os.Hostname is used instead. This has no effect
+// on the other formats.
+func WithTextLogHostname(hostname string) TextLogOption {
+	return func(config *textLogConfig) {
+		if hostname == "" {
+			hostname, _ = os.Hostname()
+		}
+		config.hostname = hostname
+	}
+}
+
+// WithTextLogExcludeUserAgents prevents TextLog from logging requests whose
+// User-Agent header contains any of the given strings, e.g. to drop requests
+// from health checkers and uptime monitors. Matching is case-sensitive.
+// Excluded requests are not logged even if they are slow or fail.
+func WithTextLogExcludeUserAgents(substrings ...string) TextLogOption {
+	return func(config *textLogConfig) {
+		for i := range substrings {
+			if substrings[i] != "" {
+				config.excludeUAs = append(config.excludeUAs, substrings[i])
+			}
+		}
+	}
+}
+
+// WithTextLogLifecycle sets whether TextLog should log an additional line when
+// it starts processing each request, before the usual line when the request is
+// completed. This can help when debugging requests that hang.
+//
+// When enabled, if the request has an X-Request-Id header its value will be
+// appended to both lines so they can be correlated.
+func WithTextLogLifecycle(lifecycle bool) TextLogOption {
+	return func(config *textLogConfig) {
+		config.lifecycle = lifecycle
+	}
+}
+
 // TextLog logs details of each request in a textual format.
 //
 // By default each request will be logged to stdout in the 'common' log format.
@@ -49,57 +238,288 @@ func TextLog(opts ...TextLogOption) func(http.Handler) http.Handler {
 		sink: func(s string) {
 			fmt.Printf(s)
 		},
-		format: TextLogFormatCommon,
-		clock:  time.Now,
+		format:     TextLogFormatCommon,
+		clock:      time.Now,
+		since:      time.Since,
+		timeFormat: "02/Jan/2006:15:04:05 -0700",
+		sampleRate: 1,
+		sampler:    rand.Float64,
 	}
 
 	for _, opt := range opts {
 		opt(conf)
 	}
 
+	if conf.buffer != nil {
+		conf.buffer.start(conf.sink)
+		conf.sink = conf.buffer.write
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			wrapped := &textLogWrapper{
+			if conf.excluded(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &statusRecorder{
 				ResponseWriter: w,
 			}
-			start := conf.clock()
+			entry := &textLogEntry{
+				request: r,
+				start:   conf.clock(),
+				began:   time.Now(),
+				format:  conf.format,
+			}
+			if conf.formatFunc != nil {
+				entry.format = conf.formatFunc(r)
+			}
+
+			// Decide up-front so that lifecycle lines are sampled consistently
+			sampled := conf.sampleRate >= 1 || conf.sampler() < conf.sampleRate
+
+			if conf.lifecycle {
+				entry.requestID = r.Header.Get("X-Request-Id")
+				if sampled {
+					conf.sink(formatTextLogStart(conf, entry))
+				}
+			}
+
 			next.ServeHTTP(wrapped, r)
-			conf.sink(formatTextLog(conf.format, r, wrapped.status, wrapped.written, start))
+			entry.duration = conf.since(entry.began)
+
+			if conf.slowFunc != nil {
+				threshold := conf.slowFunc(r)
+				entry.slow = threshold > 0 && entry.duration >= threshold
+			}
+
+			if !sampled && !entry.slow && wrapped.status < http.StatusInternalServerError {
+				return
+			}
+
+			entry.status = wrapped.status
+			entry.written = wrapped.written
+			entry.header = wrapped.Header()
+			if conf.countHeaders {
+				entry.written += estimateHeaderBytes(textLogProto(r), wrapped.status, wrapped.Header())
+			}
+			conf.sink(formatTextLog(conf, entry))
 		})
 	}
 }
 
-func formatTextLog(format TextLogFormat, r *http.Request, status int, written int, start time.Time) string {
-	switch format {
-	case TextLogFormatCommon:
-		address := r.RemoteAddr
-		if ip, _, err := net.SplitHostPort(address); err == nil {
-			address = ip
+// excluded determines whether the request's User-Agent matches one of the
+// excluded substrings.
+func (c *textLogConfig) excluded(r *http.Request) bool {
+	if len(c.excludeUAs) == 0 {
+		return false
+	}
+
+	userAgent := r.UserAgent()
+	for i := range c.excludeUAs {
+		if strings.Contains(userAgent, c.excludeUAs[i]) {
+			return true
 		}
-		return fmt.Sprintf(
-			`%s - - %s "%s %s %s" %d %d`,
-			address,
-			start.Format("[02/Jan/2006:15:04:05 -0700]"),
-			escapeLogValue(r.Method),
-			escapeLogValue(r.URL.String()),
-			escapeLogValue(r.Proto),
-			status,
-			written,
-		)
+	}
+	return false
+}
+
+// estimateHeaderBytes estimates the size of a serialised status line and set
+// of headers.
+func estimateHeaderBytes(proto string, status int, header http.Header) int {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// e.g. "HTTP/1.1 200 OK\r\n"
+	size := len(proto) + len(fmt.Sprintf(" %03d ", status)) + len(http.StatusText(status)) + 2
+	for k, values := range header {
+		for _, v := range values {
+			// e.g. "Key: Value\r\n"
+			size += len(k) + 2 + len(v) + 2
+		}
+	}
+	// Blank line separating headers from the body
+	return size + 2
+}
+
+// textLogEntry contains the details of a single request to be logged.
+//
+// The start time is taken from the configured clock and is used for the
+// logged timestamp, while the duration is measured from a separate reading
+// of the monotonic clock so that it isn't affected by changes to the wall
+// clock.
+type textLogEntry struct {
+	request   *http.Request
+	format    TextLogFormat
+	start     time.Time
+	began     time.Time
+	duration  time.Duration
+	status    int
+	written   int
+	requestID string
+	header    http.Header
+	slow      bool
+}
+
+func formatTextLog(conf *textLogConfig, e *textLogEntry) string {
+	var line string
+	switch e.format {
+	case TextLogFormatCommon:
+		line = formatCommonTextLog(conf, e)
 
 	case TextLogFormatCombined:
-		return fmt.Sprintf(
+		line = fmt.Sprintf(
 			`%s "%s" "%s"`,
-			formatTextLog(TextLogFormatCommon, r, status, written, start),
-			escapeLogValue(r.Referer()),
-			escapeLogValue(r.UserAgent()),
+			formatCommonTextLog(conf, e),
+			escapeLogValue(e.request.Referer()),
+			escapeLogValue(e.request.UserAgent()),
+		)
+
+	case TextLogFormatJSON:
+		fields := []string{
+			jsonLogField("status", e.status),
+			jsonLogField("bytes", e.written),
+			jsonLogField("referer", e.request.Referer()),
+			jsonLogField("user_agent", e.request.UserAgent()),
+		}
+		if len(conf.respHeaders) > 0 {
+			var headers []string
+			for _, name := range conf.respHeaders {
+				headers = append(headers, jsonLogField(http.CanonicalHeaderKey(name), e.header.Get(name)))
+			}
+			fields = append(fields, `"response_headers":{`+strings.Join(headers, ",")+"}")
+		}
+		if conf.slowFunc != nil {
+			fields = append(fields, jsonLogField("slow", e.slow))
+		}
+		return formatJSONTextLog(conf, e, fields)
+
+	default:
+		return fmt.Sprintf("Unknown text log format: %d", e.format)
+	}
+
+	for _, name := range conf.respHeaders {
+		line += fmt.Sprintf(` "%s"`, escapeLogValue(e.header.Get(name)))
+	}
+	if e.slow {
+		line += " slow"
+	}
+	if e.requestID != "" {
+		line += fmt.Sprintf(` "%s"`, escapeLogValue(e.requestID))
+	}
+	return line
+}
+
+// formatTextLogStart formats the line logged when a request starts, if
+// WithTextLogLifecycle is enabled.
+func formatTextLogStart(conf *textLogConfig, e *textLogEntry) string {
+	if e.format == TextLogFormatJSON {
+		return formatJSONTextLog(conf, e, []string{jsonLogField("event", "start")})
+	}
+
+	line := formatTextLogRequest(conf, e) + " start"
+	if e.requestID != "" {
+		line += fmt.Sprintf(` "%s"`, escapeLogValue(e.requestID))
+	}
+	return line
+}
+
+func formatCommonTextLog(conf *textLogConfig, e *textLogEntry) string {
+	return fmt.Sprintf(
+		`%s %d %d`,
+		formatTextLogRequest(conf, e),
+		e.status,
+		e.written,
+	)
+}
+
+// formatTextLogRequest formats the client address, timestamp and request line,
+// which are common to the Apache-style log formats.
+func formatTextLogRequest(conf *textLogConfig, e *textLogEntry) string {
+	return fmt.Sprintf(
+		`%s - - [%s] "%s %s %s"`,
+		textLogAddress(e.request),
+		textLogTime(conf, e.start),
+		escapeLogValue(e.request.Method),
+		escapeLogValue(textLogTarget(e.request)),
+		escapeLogValue(textLogProto(e.request)),
+	)
+}
+
+// formatJSONTextLog formats a JSON object containing the details of the
+// request, followed by the given extra fields.
+func formatJSONTextLog(conf *textLogConfig, e *textLogEntry, extra []string) string {
+	fields := []string{
+		jsonLogField("remote_addr", textLogAddress(e.request)),
+		jsonLogField("time", textLogTime(conf, e.start)),
+		jsonLogField("method", e.request.Method),
+	}
+	if conf.splitURL {
+		fields = append(fields,
+			jsonLogField("path", e.request.URL.EscapedPath()),
+			jsonLogField("query", e.request.URL.RawQuery),
 		)
+	} else {
+		fields = append(fields, jsonLogField("url", textLogTarget(e.request)))
+	}
+	fields = append(fields, jsonLogField("proto", textLogProto(e.request)))
+	if conf.hostname != "" {
+		fields = append(fields, jsonLogField("host", conf.hostname))
+	}
+	fields = append(fields, extra...)
+	if e.requestID != "" {
+		fields = append(fields, jsonLogField("request_id", e.requestID))
+	}
+	return "{" + strings.Join(fields, ",") + "}"
+}
 
+func jsonLogField(name string, value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf(`"%s":"%s"`, name, escapeJSONLogValue(v))
 	default:
-		return fmt.Sprintf("Unknown text log format: %d", format)
+		return fmt.Sprintf(`"%s":%v`, name, v)
 	}
 }
 
+func textLogAddress(r *http.Request) string {
+	address := r.RemoteAddr
+	if ip, _, err := net.SplitHostPort(address); err == nil {
+		address = ip
+	}
+	return address
+}
+
+// textLogTarget returns the target of the request as it would appear in an
+// HTTP/1.1 request line. Requests are always logged in origin form (the path
+// and query), so the same request is logged identically whether it was made
+// with an absolute URL, or over HTTP/2 where the target is split across
+// pseudo-headers. CONNECT requests, which have no path, are logged with their
+// authority.
+func textLogTarget(r *http.Request) string {
+	if r.Method == http.MethodConnect && r.URL.Path == "" {
+		return r.Host
+	}
+	return r.URL.RequestURI()
+}
+
+// textLogProto returns the protocol of the request, falling back to the
+// version numbers if the Proto field hasn't been populated.
+func textLogProto(r *http.Request) string {
+	if r.Proto == "" && r.ProtoMajor > 0 {
+		return fmt.Sprintf("HTTP/%d.%d", r.ProtoMajor, r.ProtoMinor)
+	}
+	return r.Proto
+}
+
+func textLogTime(conf *textLogConfig, t time.Time) string {
+	if conf.utc {
+		t = t.UTC()
+	}
+	return t.Format(conf.timeFormat)
+}
+
 func escapeLogValue(s string) string {
 	var result strings.Builder
 	for _, r := range s {
@@ -133,30 +553,32 @@ func escapeLogValue(s string) string {
 	return result.String()
 }
 
-type textLogWrapper struct {
-	http.ResponseWriter
-	written int
-	status  int
-	headers bool
-}
-
-func (t *textLogWrapper) WriteHeader(code int) {
-	t.headers = true
-	t.status = code
-	t.ResponseWriter.WriteHeader(code)
-}
-
-func (t *textLogWrapper) Write(b []byte) (int, error) {
-	if !t.headers {
-		t.WriteHeader(http.StatusOK)
-	}
-	n, err := t.ResponseWriter.Write(b)
-	t.written += n
-	return n, err
-}
-
-func (t *textLogWrapper) Flush() {
-	if flusher, ok := t.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
+// escapeJSONLogValue escapes a value so that it can be used within a JSON
+// string. Unlike escapeLogValue, non-ASCII characters are left intact, and
+// control characters use \u escapes.
+func escapeJSONLogValue(s string) string {
+	var result strings.Builder
+	for _, r := range s {
+		switch r {
+		case '"':
+			result.WriteString(`\"`)
+		case '\\':
+			result.WriteString(`\\`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\t':
+			result.WriteString(`\t`)
+		case '\r':
+			result.WriteString(`\r`)
+		case '\u2028', '\u2029':
+			result.WriteString(fmt.Sprintf(`\u%04x`, r))
+		default:
+			if r < 32 || r == 127 {
+				result.WriteString(fmt.Sprintf(`\u%04x`, r))
+			} else {
+				result.WriteRune(r)
+			}
+		}
 	}
+	return result.String()
 }
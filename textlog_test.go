@@ -1,12 +1,20 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func withTestClock(t time.Time) TextLogOption {
@@ -115,6 +123,38 @@ func TestEscapeLogValue(t *testing.T) {
 	}
 }
 
+func TestEscapeJSONLogValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"normal text", "normal text"},
+		{`quote"test`, `quote\"test`},
+		{`backslash\test`, `backslash\\test`},
+		{"newline\ntest", `newline\ntest`},
+		{"tab\ttest", `tab\ttest`},
+		{"carriage\rreturn", `carriage\rreturn`},
+		{"control\x01char", `control\u0001char`},
+		{"delete\x7fchar", `delete\u007fchar`},
+		{"unicode\u00a0char", "unicode\u00a0char"},
+		{"emoji 🎉", "emoji 🎉"},
+		{"separator\u2028char", `separator\u2028char`},
+		{"invalid\xffutf8", "invalid�utf8"},
+		{"<script>", "<script>"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := escapeJSONLogValue(tt.input)
+			assert.Equal(t, tt.expected, result)
+
+			var decoded string
+			require.NoError(t, json.Unmarshal([]byte(`"`+result+`"`), &decoded))
+		})
+	}
+}
+
 func TestTextLog_WriteWithoutHeaders(t *testing.T) {
 	var logOutput string
 	sink := func(s string) {
@@ -167,3 +207,780 @@ func TestTextLog_MultipleWrites(t *testing.T) {
 	assert.Equal(t, expected, logOutput)
 	assert.Equal(t, "hello world", rr.Body.String())
 }
+
+func TestTextLog_Lifecycle(t *testing.T) {
+	var logOutput []string
+	sink := func(s string) {
+		logOutput = append(logOutput, s)
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogLifecycle(true), withTestClock(testTime))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Len(t, logOutput, 1)
+		w.Write([]byte("test content"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" start`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 200 12`,
+	}, logOutput)
+}
+
+func TestTextLog_LifecycleWithRequestID(t *testing.T) {
+	var logOutput []string
+	sink := func(s string) {
+		logOutput = append(logOutput, s)
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogLifecycle(true), withTestClock(testTime))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	req.Header.Set("X-Request-Id", "abc123")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" start "abc123"`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 204 0 "abc123"`,
+	}, logOutput)
+}
+
+func TestTextLog_LifecycleDisabled(t *testing.T) {
+	calls := 0
+	sink := func(s string) {
+		calls++
+	}
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogLifecycle(false))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestTextLog_TimeFormat(t *testing.T) {
+	var logOutput string
+	sink := func(s string) {
+		logOutput = s
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogTimeFormat(time.RFC3339), withTestClock(testTime))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := `127.0.0.1 - - [2000-10-10T13:55:36-07:00] "GET /test HTTP/1.1" 200 0`
+	assert.Equal(t, expected, logOutput)
+}
+
+func TestTextLog_UTC(t *testing.T) {
+	var logOutput string
+	sink := func(s string) {
+		logOutput = s
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogUTC(true), withTestClock(testTime))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := `127.0.0.1 - - [10/Oct/2000:20:55:36 +0000] "GET /test HTTP/1.1" 200 0`
+	assert.Equal(t, expected, logOutput)
+}
+
+func TestTextLog_JSONFormat(t *testing.T) {
+	var logOutput string
+	sink := func(s string) {
+		logOutput = s
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	handler := TextLog(WithTextLogSink(sink), WithTextLogFormat(TextLogFormatJSON), withTestClock(testTime))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hello World!"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	req.Header.Set("Referer", "http://evil.com\"\n<script>")
+	req.Header.Set("User-Agent", "Bøt\\1.0\t")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/test","proto":"HTTP/1.1","status":200,"bytes":12,"referer":"http://evil.com\"\n<script>","user_agent":"Bøt\\1.0\t"}`
+	assert.Equal(t, expected, logOutput)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal([]byte(logOutput), &decoded))
+	assert.Equal(t, "http://evil.com\"\n<script>", decoded["referer"])
+	assert.Equal(t, "Bøt\\1.0\t", decoded["user_agent"])
+}
+
+func TestTextLog_JSONFormatLifecycle(t *testing.T) {
+	var logOutput []string
+	sink := func(s string) {
+		logOutput = append(logOutput, s)
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.UTC)
+
+	handler := TextLog(
+		WithTextLogSink(sink),
+		WithTextLogFormat(TextLogFormatJSON),
+		WithTextLogTimeFormat(time.RFC3339),
+		WithTextLogLifecycle(true),
+		withTestClock(testTime),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+	req.Header.Set("X-Request-Id", "abc123")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, []string{
+		`{"remote_addr":"127.0.0.1","time":"2000-10-10T13:55:36Z","method":"GET","url":"/test","proto":"HTTP/1.1","event":"start","request_id":"abc123"}`,
+		`{"remote_addr":"127.0.0.1","time":"2000-10-10T13:55:36Z","method":"GET","url":"/test","proto":"HTTP/1.1","status":204,"bytes":0,"referer":"","user_agent":"","request_id":"abc123"}`,
+	}, logOutput)
+}
+
+func TestTextLog_MultipleSinks(t *testing.T) {
+	var first, second string
+
+	handler := TextLog(WithTextLogSinks(
+		func(s string) {
+			first = s
+		},
+		func(s string) {
+			panic("broken sink")
+		},
+		func(s string) {
+			second = s
+		},
+	))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, second)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestTextLog_CountHeaders(t *testing.T) {
+	var withoutHeaders, withHeaders string
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Custom", "value")
+		w.Header().Add("Set-Cookie", "a=b")
+		w.Header().Add("Set-Cookie", "c=d")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test content"))
+	})
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Proto = "HTTP/1.1"
+
+	TextLog(WithTextLogSink(func(s string) { withoutHeaders = s }), withTestClock(testTime))(next).ServeHTTP(httptest.NewRecorder(), req)
+	TextLog(WithTextLogSink(func(s string) { withHeaders = s }), WithTextLogCountHeaders(true), withTestClock(testTime))(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Status line:             "HTTP/1.1 200 OK\r\n"          = 17
+	// Content-Type header:     "Content-Type: text/plain\r\n" = 26
+	// X-Custom header:         "X-Custom: value\r\n"          = 17
+	// Set-Cookie headers:      "Set-Cookie: a=b\r\n" x 2      = 34
+	// Blank line:              "\r\n"                         = 2
+	// Body:                                                   = 12
+	assert.Equal(t, `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 200 12`, withoutHeaders)
+	assert.Equal(t, `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 200 108`, withHeaders)
+}
+
+func withTextLogSampler(sampler func() float64) TextLogOption {
+	return func(config *textLogConfig) {
+		config.sampler = sampler
+	}
+}
+
+func TestTextLog_SampleRate(t *testing.T) {
+	var lines []string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { lines = append(lines, s) }),
+		WithTextLogSampleRate(0.25),
+		withTextLogSampler(rand.New(rand.NewSource(42)).Float64),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	expected := rand.New(rand.NewSource(42))
+	var want []string
+	for i := 0; i < 100; i++ {
+		path := fmt.Sprintf("/%d", i)
+		if expected.Float64() < 0.25 {
+			want = append(want, path)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var got []string
+	for _, line := range lines {
+		got = append(got, strings.Fields(line)[6])
+	}
+	assert.Equal(t, want, got)
+	assert.NotEmpty(t, got)
+	assert.Less(t, len(got), 100)
+}
+
+func TestTextLog_SampleRateAlwaysLogsErrors(t *testing.T) {
+	var lines []string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { lines = append(lines, s) }),
+		WithTextLogSampleRate(0),
+		WithTextLogLifecycle(true),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadGateway)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	assert.Empty(t, lines)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/error", nil))
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"GET /error HTTP/1.1" 502 0`)
+}
+
+func TestTextLog_ResponseHeaders(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	tests := []struct {
+		name     string
+		format   TextLogFormat
+		expected string
+	}{
+		{
+			name:     "common format",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 200 2 "application/json" ""`,
+		},
+		{
+			name:     "json format",
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/test","proto":"HTTP/1.1","status":200,"bytes":2,"referer":"","user_agent":"","response_headers":{"Content-Type":"application/json","Cache-Control":""}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput string
+			handler := TextLog(
+				WithTextLogSink(func(s string) { logOutput = s }),
+				WithTextLogFormat(tt.format),
+				WithTextLogResponseHeaders("content-type", "Cache-Control"),
+				withTestClock(testTime),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("{}"))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "127.0.0.1:8080"
+			req.Header.Del("User-Agent")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, logOutput)
+		})
+	}
+}
+
+func TestTextLog_SplitURL(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	tests := []struct {
+		name     string
+		format   TextLogFormat
+		expected string
+	}{
+		{
+			name:     "json format",
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","path":"/search","query":"q=go","proto":"HTTP/1.1","status":200,"bytes":0,"referer":"","user_agent":""}`,
+		},
+		{
+			name:     "common format",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search?q=go HTTP/1.1" 200 0`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput string
+			handler := TextLog(
+				WithTextLogSink(func(s string) { logOutput = s }),
+				WithTextLogFormat(tt.format),
+				WithTextLogSplitURL(true),
+				withTestClock(testTime),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/search?q=go", nil)
+			req.RemoteAddr = "127.0.0.1:8080"
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, logOutput)
+		})
+	}
+}
+
+func TestTextLog_Informational(t *testing.T) {
+	var logOutput string
+	handler := TextLog(WithTextLogSink(func(s string) { logOutput = s }))(earlyHintsHandler)
+
+	informational, res, _ := serveWithInformational(t, handler, nil)
+
+	require.Contains(t, informational, http.StatusEarlyHints)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, logOutput, `" 200 12`)
+}
+
+// withTextLogDuration makes every request appear to take the given duration.
+func withTextLogDuration(d time.Duration) TextLogOption {
+	return func(config *textLogConfig) {
+		config.since = func(time.Time) time.Duration {
+			return d
+		}
+	}
+}
+
+func TestTextLog_SlowThresholdFunc(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	tests := []struct {
+		name     string
+		path     string
+		format   TextLogFormat
+		expected string
+	}{
+		{
+			name:     "fast path over threshold",
+			path:     "/ping",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /ping HTTP/1.1" 200 0 slow`,
+		},
+		{
+			name:     "slow path under threshold",
+			path:     "/search",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search HTTP/1.1" 200 0`,
+		},
+		{
+			name:     "no threshold",
+			path:     "/other",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /other HTTP/1.1" 200 0`,
+		},
+		{
+			name:     "json format slow",
+			path:     "/ping",
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/ping","proto":"HTTP/1.1","status":200,"bytes":0,"referer":"","user_agent":"","slow":true}`,
+		},
+		{
+			name:     "json format not slow",
+			path:     "/search",
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/search","proto":"HTTP/1.1","status":200,"bytes":0,"referer":"","user_agent":"","slow":false}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput string
+			handler := TextLog(
+				WithTextLogSink(func(s string) { logOutput = s }),
+				WithTextLogFormat(tt.format),
+				WithTextLogSlowThresholdFunc(func(r *http.Request) time.Duration {
+					switch r.URL.Path {
+					case "/ping":
+						return 100 * time.Millisecond
+					case "/search":
+						return 5 * time.Second
+					default:
+						return 0
+					}
+				}),
+				withTestClock(testTime),
+				withTextLogDuration(time.Second),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.RemoteAddr = "127.0.0.1:8080"
+			req.Header.Del("User-Agent")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, logOutput)
+		})
+	}
+}
+
+func TestTextLog_SlowThreshold(t *testing.T) {
+	var lines []string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { lines = append(lines, s) }),
+		WithTextLogSlowThreshold(500*time.Millisecond),
+		WithTextLogSampleRate(0),
+		withTextLogDuration(time.Second),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	// Slow requests are logged regardless of sampling
+	require.Len(t, lines, 1)
+	assert.True(t, strings.HasSuffix(lines[0], " slow"))
+}
+
+func TestTextLog_Hostname(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	tests := []struct {
+		name     string
+		format   TextLogFormat
+		expected string
+	}{
+		{
+			name:     "json format",
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/test","proto":"HTTP/1.1","host":"web-1","status":200,"bytes":0,"referer":"","user_agent":""}`,
+		},
+		{
+			name:     "common format",
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /test HTTP/1.1" 200 0`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput string
+			handler := TextLog(
+				WithTextLogSink(func(s string) { logOutput = s }),
+				WithTextLogFormat(tt.format),
+				WithTextLogHostname("web-1"),
+				withTestClock(testTime),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = "127.0.0.1:8080"
+			req.Header.Del("User-Agent")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, logOutput)
+		})
+	}
+}
+
+func TestTextLog_HostnameDefault(t *testing.T) {
+	expected, err := os.Hostname()
+	require.NoError(t, err)
+
+	var logOutput string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { logOutput = s }),
+		WithTextLogFormat(TextLogFormatJSON),
+		WithTextLogHostname(""),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(logOutput), &entry))
+	assert.Equal(t, expected, entry["host"])
+}
+
+func TestTextLog_DurationIgnoresClock(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	// A clock that jumps backwards each time it's called
+	now := testTime
+	backwards := func(config *textLogConfig) {
+		config.clock = func() time.Time {
+			now = now.Add(-time.Hour)
+			return now
+		}
+	}
+
+	var logOutput string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { logOutput = s }),
+		WithTextLogSlowThreshold(time.Nanosecond),
+		backwards,
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, `127.0.0.1 - - [10/Oct/2000:12:55:36 -0700] "GET /test HTTP/1.1" 200 0 slow`, logOutput)
+}
+
+func TestTextLog_FormatFunc(t *testing.T) {
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	var lines []string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { lines = append(lines, s) }),
+		WithTextLogFormat(TextLogFormatJSON),
+		WithTextLogFormatFunc(func(r *http.Request) TextLogFormat {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				return TextLogFormatCombined
+			}
+			return TextLogFormatCommon
+		}),
+		withTestClock(testTime),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/api/x", "/static/y"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "127.0.0.1:8080"
+		req.Header.Set("Referer", "http://example.com/")
+		req.Header.Set("User-Agent", "TestAgent/1.0")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, []string{
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /api/x HTTP/1.1" 200 0 "http://example.com/" "TestAgent/1.0"`,
+		`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /static/y HTTP/1.1" 200 0`,
+	}, lines)
+}
+
+func TestTextLog_SinkErr(t *testing.T) {
+	sinkErr := errors.New("disk full")
+
+	var lines []string
+	var errs []error
+	handler := TextLog(
+		WithTextLogSinkErrorHandler(func(err error) { errs = append(errs, err) }),
+		WithTextLogSinkErr(func(s string) error {
+			lines = append(lines, s)
+			if strings.Contains(s, "/fail") {
+				return sinkErr
+			}
+			return nil
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	assert.Len(t, lines, 1)
+	assert.Empty(t, errs)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	assert.Len(t, lines, 2)
+	assert.Equal(t, []error{sinkErr}, errs)
+}
+
+func TestTextLog_SinkErrWithoutHandler(t *testing.T) {
+	called := false
+	handler := TextLog(WithTextLogSinkErr(func(s string) error {
+		called = true
+		return errors.New("disk full")
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	})
+	assert.True(t, called)
+}
+
+func TestTextLog_RequestLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  *http.Request
+		format   TextLogFormat
+		expected string
+	}{
+		{
+			name: "http2",
+			request: &http.Request{
+				Method:     "GET",
+				URL:        &url.URL{Path: "/search", RawQuery: "q=go"},
+				Proto:      "HTTP/2.0",
+				ProtoMajor: 2,
+				Host:       "example.com",
+				RemoteAddr: "127.0.0.1:8080",
+				Header:     http.Header{},
+			},
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search?q=go HTTP/2.0" 200 0`,
+		},
+		{
+			name: "http2 json",
+			request: &http.Request{
+				Method:     "GET",
+				URL:        &url.URL{Path: "/search", RawQuery: "q=go"},
+				Proto:      "HTTP/2.0",
+				ProtoMajor: 2,
+				Host:       "example.com",
+				RemoteAddr: "127.0.0.1:8080",
+				Header:     http.Header{},
+			},
+			format:   TextLogFormatJSON,
+			expected: `{"remote_addr":"127.0.0.1","time":"10/Oct/2000:13:55:36 -0700","method":"GET","url":"/search?q=go","proto":"HTTP/2.0","status":200,"bytes":0,"referer":"","user_agent":""}`,
+		},
+		{
+			name: "http2 connect",
+			request: &http.Request{
+				Method:     "CONNECT",
+				URL:        &url.URL{Host: "example.com:443"},
+				Proto:      "HTTP/2.0",
+				ProtoMajor: 2,
+				Host:       "example.com:443",
+				RemoteAddr: "127.0.0.1:8080",
+				Header:     http.Header{},
+			},
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "CONNECT example.com:443 HTTP/2.0" 200 0`,
+		},
+		{
+			name: "absolute form",
+			request: &http.Request{
+				Method:     "GET",
+				URL:        &url.URL{Scheme: "http", Host: "example.com", Path: "/search"},
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Host:       "example.com",
+				RemoteAddr: "127.0.0.1:8080",
+				Header:     http.Header{},
+			},
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search HTTP/1.1" 200 0`,
+		},
+		{
+			name: "missing proto",
+			request: &http.Request{
+				Method:     "GET",
+				URL:        &url.URL{Path: "/"},
+				ProtoMajor: 2,
+				Host:       "example.com",
+				RemoteAddr: "127.0.0.1:8080",
+				Header:     http.Header{},
+			},
+			format:   TextLogFormatCommon,
+			expected: `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/2.0" 200 0`,
+		},
+	}
+
+	testTime := time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("PDT", -7*3600))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logOutput string
+			handler := TextLog(
+				WithTextLogSink(func(s string) { logOutput = s }),
+				WithTextLogFormat(tt.format),
+				withTestClock(testTime),
+			)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), tt.request)
+
+			assert.Equal(t, tt.expected, logOutput)
+		})
+	}
+}
+
+func TestTextLog_ExcludeUserAgents(t *testing.T) {
+	var lines []string
+	handler := TextLog(
+		WithTextLogSink(func(s string) { lines = append(lines, s) }),
+		WithTextLogFormat(TextLogFormatCombined),
+		WithTextLogLifecycle(true),
+		WithTextLogExcludeUserAgents("kube-probe/", "UptimeRobot"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	for _, userAgent := range []string{"kube-probe/1.29", "Mozilla/5.0 (compatible; UptimeRobot/2.0)", "Mozilla/5.0", ""} {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("User-Agent", userAgent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], " start")
+	assert.True(t, strings.HasSuffix(lines[1], `"Mozilla/5.0"`))
+	assert.Contains(t, lines[2], " start")
+	assert.True(t, strings.HasSuffix(lines[3], `"" ""`))
+}
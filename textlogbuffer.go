@@ -0,0 +1,94 @@
+package middleware
+
+import "sync"
+
+// TextLogBuffer decouples TextLog from its sink, so that a slow sink doesn't
+// add latency to requests. Lines are queued in a buffered channel and written
+// to the sink by a background goroutine. Create one with NewTextLogBuffer and
+// pass it to TextLog using WithTextLogBuffered.
+type TextLogBuffer struct {
+	lines chan string
+	block bool
+	done  chan struct{}
+
+	mutex   sync.RWMutex
+	started bool
+	closed  bool
+}
+
+// NewTextLogBuffer creates a new TextLogBuffer that can queue up to size lines.
+// If block is true, requests wait for space in the queue when it is full;
+// otherwise, lines that don't fit are dropped.
+func NewTextLogBuffer(size int, block bool) *TextLogBuffer {
+	return &TextLogBuffer{
+		lines: make(chan string, size),
+		block: block,
+		done:  make(chan struct{}),
+	}
+}
+
+// WithTextLogBuffered makes TextLog write lines to its sink (or sinks) via the
+// given buffer, instead of synchronously while handling the request. Each
+// buffer can only be used by a single TextLog middleware; TextLog panics if it
+// is given a buffer that is already in use.
+func WithTextLogBuffered(buffer *TextLogBuffer) TextLogOption {
+	return func(config *textLogConfig) {
+		config.buffer = buffer
+	}
+}
+
+// start begins writing queued lines to the sink in the background.
+func (b *TextLogBuffer) start(sink func(string)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.started {
+		panic("middleware: TextLogBuffer used by multiple TextLog middleware")
+	}
+	b.started = true
+
+	go func() {
+		defer close(b.done)
+		for line := range b.lines {
+			sink(line)
+		}
+	}()
+}
+
+// write queues a line to be written to the sink. Lines written after the
+// buffer has been closed are dropped.
+func (b *TextLogBuffer) write(line string) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	if b.block {
+		b.lines <- line
+		return
+	}
+
+	select {
+	case b.lines <- line:
+	default:
+	}
+}
+
+// Close stops accepting new lines, and waits for all queued lines to be written
+// to the sink. It should be called when shutting down, after the server has
+// stopped handling requests, to avoid losing log lines.
+func (b *TextLogBuffer) Close() {
+	b.mutex.Lock()
+	started := b.started
+	if !b.closed {
+		b.closed = true
+		close(b.lines)
+	}
+	b.mutex.Unlock()
+
+	if started {
+		<-b.done
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextLogBuffer_Delivers(t *testing.T) {
+	var mutex sync.Mutex
+	var lines []string
+
+	buffer := NewTextLogBuffer(10, true)
+	handler := TextLog(
+		WithTextLogSink(func(s string) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			lines = append(lines, s)
+		}),
+		WithTextLogBuffered(buffer),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/one", "/two", "/three"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	buffer.Close()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], "GET /one")
+		assert.Contains(t, lines[1], "GET /two")
+		assert.Contains(t, lines[2], "GET /three")
+	}
+}
+
+func TestTextLogBuffer_Drop(t *testing.T) {
+	received := make(chan string)
+	release := make(chan struct{})
+	var lines []string
+
+	buffer := NewTextLogBuffer(1, false)
+	buffer.start(func(s string) {
+		received <- s
+		<-release
+		lines = append(lines, s)
+	})
+
+	buffer.write("one")
+	assert.Equal(t, "one", <-received)
+
+	// The sink is busy with the first line, so this fills the buffer...
+	buffer.write("two")
+	// ...and this is dropped
+	buffer.write("three")
+
+	close(release)
+	go func() {
+		for range received {
+		}
+	}()
+	buffer.Close()
+	close(received)
+
+	assert.Equal(t, []string{"one", "two"}, lines)
+}
+
+func TestTextLogBuffer_Block(t *testing.T) {
+	release := make(chan struct{})
+	var lines []string
+
+	buffer := NewTextLogBuffer(1, true)
+	buffer.start(func(s string) {
+		<-release
+		lines = append(lines, s)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, line := range []string{"one", "two", "three"} {
+			buffer.write(line)
+		}
+	}()
+
+	close(release)
+	<-done
+	buffer.Close()
+
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+}
+
+func TestTextLogBuffer_WriteAfterClose(t *testing.T) {
+	var lines []string
+
+	buffer := NewTextLogBuffer(1, true)
+	buffer.start(func(s string) {
+		lines = append(lines, s)
+	})
+	buffer.Close()
+	buffer.write("late")
+	buffer.Close()
+
+	assert.Empty(t, lines)
+}
+
+func TestTextLogBuffer_SharedPanics(t *testing.T) {
+	buffer := NewTextLogBuffer(1, true)
+	defer buffer.Close()
+
+	TextLog(WithTextLogBuffered(buffer))
+	assert.Panics(t, func() {
+		TextLog(WithTextLogBuffered(buffer))
+	})
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+type timeoutConfig struct {
+	timeout   time.Duration
+	panicDump func(*http.Request, []byte)
+}
+
+type TimeoutOption func(*timeoutConfig)
+
+// WithTimeout sets how long the next handler has to respond before the request
+// is killed. Defaults to 30 seconds.
+func WithTimeout(timeout time.Duration) TimeoutOption {
+	return func(config *timeoutConfig) {
+		config.timeout = timeout
+	}
+}
+
+// WithTimeoutPanicDump sets a function to be called when a request is killed
+// for taking too long. It is given the stack traces of all goroutines at that
+// moment, in the same format as runtime.Stack, which can help find where
+// handlers are hanging.
+//
+// Capturing the stacks briefly stops the world, so this is best used when
+// timeouts are rare.
+func WithTimeoutPanicDump(dump func(*http.Request, []byte)) TimeoutOption {
+	return func(config *timeoutConfig) {
+		config.panicDump = dump
+	}
+}
+
+// Timeout is a middleware that limits how long the next handler can take to
+// respond. The request's context is given a deadline, and if the handler hasn't
+// finished when it passes, a 503 Service Unavailable response is sent instead.
+// Chain this middleware with ErrorHandler to customise this.
+//
+// The handler's response is buffered until it completes, so this is not
+// suitable for streaming responses. Informational (1xx) responses such as 103
+// Early Hints are discarded. Once a request has timed out, further writes by
+// the handler will return http.ErrHandlerTimeout.
+func Timeout(opts ...TimeoutOption) func(http.Handler) http.Handler {
+	config := &timeoutConfig{
+		timeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), config.timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			wrapped := &timeoutWrapper{
+				header: make(http.Header),
+			}
+			done := make(chan struct{})
+			panics := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panics <- p
+					}
+				}()
+				next.ServeHTTP(wrapped, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panics:
+				panic(p)
+
+			case <-done:
+				wrapped.mutex.Lock()
+				defer wrapped.mutex.Unlock()
+
+				for k, v := range wrapped.header {
+					w.Header()[k] = v
+				}
+				if wrapped.status == 0 {
+					wrapped.status = http.StatusOK
+				}
+				w.WriteHeader(wrapped.status)
+				_, _ = w.Write(wrapped.body.Bytes())
+
+			case <-ctx.Done():
+				wrapped.mutex.Lock()
+				wrapped.timedOut = true
+				wrapped.mutex.Unlock()
+
+				if config.panicDump != nil && ctx.Err() == context.DeadlineExceeded {
+					config.panicDump(r, goroutineStacks())
+				}
+
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// goroutineStacks returns the stack traces of all goroutines.
+func goroutineStacks() []byte {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// timeoutWrapper buffers the response from the next handler, so that it can
+// be discarded if the request times out.
+type timeoutWrapper struct {
+	mutex    sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (t *timeoutWrapper) Header() http.Header {
+	return t.header
+}
+
+func (t *timeoutWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		// The real writer can't be used from the handler's goroutine, and the
+		// final response is buffered anyway, so there's no point forwarding it
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.timedOut || t.status != 0 {
+		return
+	}
+	t.status = code
+}
+
+func (t *timeoutWrapper) Write(b []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if t.status == 0 {
+		t.status = http.StatusOK
+	}
+	return t.body.Write(b)
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_FastHandler(t *testing.T) {
+	handler := Timeout(WithTimeout(time.Second))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "value")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "created", rr.Body.String())
+	assert.Equal(t, "value", rr.Header().Get("X-Test"))
+}
+
+func TestTimeout_Informational(t *testing.T) {
+	handler := Timeout(WithTimeout(time.Second))(earlyHintsHandler)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "test content", rr.Body.String())
+}
+
+func TestTimeout_ImplicitStatus(t *testing.T) {
+	handler := Timeout()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestTimeout_SlowHandler(t *testing.T) {
+	release := make(chan struct{})
+	writeErr := make(chan error, 1)
+	handler := Timeout(WithTimeout(10 * time.Millisecond))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		<-release
+		_, err := w.Write([]byte("too late"))
+		writeErr <- err
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+	close(release)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, http.ErrHandlerTimeout, <-writeErr)
+}
+
+func TestTimeout_PanicDump(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	var dumpedPath string
+	var dumpedStack []byte
+	handler := Timeout(
+		WithTimeout(10*time.Millisecond),
+		WithTimeoutPanicDump(func(r *http.Request, stack []byte) {
+			dumpedPath = r.URL.Path
+			dumpedStack = stack
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+
+	req := httptest.NewRequest("GET", "/slow", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "/slow", dumpedPath)
+	require.NotEmpty(t, dumpedStack)
+	assert.Contains(t, string(dumpedStack), "TestTimeout_PanicDump")
+}
+
+func TestTimeout_PanicDumpNotCalledForFastHandler(t *testing.T) {
+	called := false
+	handler := Timeout(
+		WithTimeout(time.Second),
+		WithTimeoutPanicDump(func(r *http.Request, stack []byte) {
+			called = true
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	assert.False(t, called)
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	handler := Timeout()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+
+	assert.PanicsWithValue(t, "oops", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	})
+}
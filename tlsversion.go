@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+type requireTLSVersionConfig struct {
+	statusCode          int
+	trustForwardedProto bool
+}
+
+type RequireTLSVersionOption func(*requireTLSVersionConfig)
+
+// WithTLSVersionStatus sets the status code used to respond to requests that
+// don't meet the minimum TLS version. Defaults to 403 Forbidden.
+func WithTLSVersionStatus(code int) RequireTLSVersionOption {
+	return func(config *requireTLSVersionConfig) {
+		config.statusCode = code
+	}
+}
+
+// WithTrustForwardedProto sets whether requests received over plain HTTP
+// should be allowed if they have an X-Forwarded-Proto header of "https". This
+// should only be enabled if the server is behind a proxy that terminates TLS
+// and enforces the minimum version itself, and that always sets the header.
+func WithTrustForwardedProto(trust bool) RequireTLSVersionOption {
+	return func(config *requireTLSVersionConfig) {
+		config.trustForwardedProto = trust
+	}
+}
+
+// RequireTLSVersion is a middleware that rejects requests that weren't made
+// using at least the given TLS version (e.g. tls.VersionTLS12), including
+// requests that weren't made over TLS at all.
+//
+// Rejected requests are responded to with a 403 response with no body, or the
+// status configured with WithTLSVersionStatus. Chain this middleware with
+// ErrorHandler to customise this.
+func RequireTLSVersion(min uint16, opts ...RequireTLSVersionOption) func(http.Handler) http.Handler {
+	config := &requireTLSVersionConfig{
+		statusCode: http.StatusForbidden,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil {
+				if !config.trustForwardedProto || !strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+					w.WriteHeader(config.statusCode)
+					return
+				}
+			} else if r.TLS.Version < min {
+				w.WriteHeader(config.statusCode)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireTLSVersion(t *testing.T) {
+	tests := []struct {
+		name           string
+		version        uint16
+		expectedStatus int
+	}{
+		{"TLS 1.0", tls.VersionTLS10, http.StatusForbidden},
+		{"TLS 1.1", tls.VersionTLS11, http.StatusForbidden},
+		{"TLS 1.2", tls.VersionTLS12, http.StatusOK},
+		{"TLS 1.3", tls.VersionTLS13, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireTLSVersion(tls.VersionTLS12)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "https://example.com/test", nil)
+			req.TLS = &tls.ConnectionState{Version: tt.version}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestRequireTLSVersion_PlainHTTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trust          bool
+		forwardedProto string
+		expectedStatus int
+	}{
+		{"no header", false, "", http.StatusForbidden},
+		{"untrusted https header", false, "https", http.StatusForbidden},
+		{"trusted without header", true, "", http.StatusForbidden},
+		{"trusted http header", true, "http", http.StatusForbidden},
+		{"trusted https header", true, "https", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireTLSVersion(tls.VersionTLS12, WithTrustForwardedProto(tt.trust))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.forwardedProto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestRequireTLSVersion_CustomStatus(t *testing.T) {
+	handler := RequireTLSVersion(tls.VersionTLS12, WithTLSVersionStatus(http.StatusUpgradeRequired))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "https://example.com/test", nil)
+	req.TLS = &tls.ConnectionState{Version: tls.VersionTLS11}
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUpgradeRequired, rr.Code)
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type traceContextKey struct{}
+
+type traceContext struct {
+	traceID  string
+	parentID string
+	spanID   string
+}
+
+type traceContextConfig struct {
+	responseHeader bool
+}
+
+type TraceContextOption func(*traceContextConfig)
+
+// WithTraceResponseHeader sets whether TraceContext should also send the
+// outgoing traceparent header in the response, so that clients can correlate
+// their requests with the trace. False by default.
+func WithTraceResponseHeader(responseHeader bool) TraceContextOption {
+	return func(config *traceContextConfig) {
+		config.responseHeader = responseHeader
+	}
+}
+
+// TraceContext is a middleware that propagates W3C Trace Context headers.
+//
+// If the request has a valid traceparent header, the trace ID is retained and
+// a new span ID is generated for this request. If the header is absent or
+// invalid, a new trace is started and any tracestate header is discarded. In
+// either case the request's traceparent header is replaced with one containing
+// the new span ID, so that handlers can pass it on to downstream services.
+//
+// The IDs can be retrieved using TraceIDFromContext, SpanIDFromContext and
+// ParentSpanIDFromContext.
+func TraceContext(opts ...TraceContextOption) func(http.Handler) http.Handler {
+	config := &traceContextConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc := &traceContext{spanID: newTraceID(8)}
+			flags := "00"
+
+			if traceID, parentID, parentFlags, ok := parseTraceParent(r.Header.Get("traceparent")); ok {
+				tc.traceID = traceID
+				tc.parentID = parentID
+				flags = parentFlags
+			} else {
+				tc.traceID = newTraceID(16)
+				r.Header.Del("tracestate")
+			}
+
+			traceParent := fmt.Sprintf("00-%s-%s-%s", tc.traceID, tc.spanID, flags)
+			r.Header.Set("traceparent", traceParent)
+			if config.responseHeader {
+				w.Header().Set("traceparent", traceParent)
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), traceContextKey{}, tc)))
+		})
+	}
+}
+
+// TraceIDFromContext returns the trace ID of the request, as set by the
+// TraceContext middleware. Returns an empty string if the middleware was not
+// used.
+func TraceIDFromContext(r *http.Request) string {
+	if tc, ok := r.Context().Value(traceContextKey{}).(*traceContext); ok {
+		return tc.traceID
+	}
+	return ""
+}
+
+// SpanIDFromContext returns the span ID generated for the request by the
+// TraceContext middleware. Returns an empty string if the middleware was not
+// used.
+func SpanIDFromContext(r *http.Request) string {
+	if tc, ok := r.Context().Value(traceContextKey{}).(*traceContext); ok {
+		return tc.spanID
+	}
+	return ""
+}
+
+// ParentSpanIDFromContext returns the span ID from the request's incoming
+// traceparent header, as parsed by the TraceContext middleware. Returns an
+// empty string if the middleware was not used, or the request started a new
+// trace.
+func ParentSpanIDFromContext(r *http.Request) string {
+	if tc, ok := r.Context().Value(traceContextKey{}).(*traceContext); ok {
+		return tc.parentID
+	}
+	return ""
+}
+
+// parseTraceParent parses and validates a traceparent header, returning the
+// trace ID, parent ID, and flags.
+func parseTraceParent(value string) (string, string, string, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return "", "", "", false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return "", "", "", false
+	}
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return "", "", "", false
+	}
+	if !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return "", "", "", false
+	}
+	if !isLowerHex(flags, 2) {
+		return "", "", "", false
+	}
+
+	return traceID, parentID, flags, true
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID(size int) string {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var traceParentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+func TestTraceContext_Propagation(t *testing.T) {
+	var traceID, spanID, parentID, traceParent, traceState string
+	handler := TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = TraceIDFromContext(r)
+		spanID = SpanIDFromContext(r)
+		parentID = ParentSpanIDFromContext(r)
+		traceParent = r.Header.Get("traceparent")
+		traceState = r.Header.Get("tracestate")
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	assert.Equal(t, "00f067aa0ba902b7", parentID)
+	assert.Regexp(t, "^[0-9a-f]{16}$", spanID)
+	assert.NotEqual(t, parentID, spanID)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spanID+"-01", traceParent)
+	assert.Equal(t, "congo=t61rcWkgMzE", traceState)
+	assert.Empty(t, rr.Header().Get("traceparent"))
+}
+
+func TestTraceContext_InvalidHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceParent string
+	}{
+		{"too few parts", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{"extra parts for version 00", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{"short trace ID", "00-4bf92f3577b34da6-00f067aa0ba902b7-01"},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{"zero parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{"invalid flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz"},
+		{"garbage", "not a traceparent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var traceID, parentID, traceParent, traceState string
+			handler := TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceID = TraceIDFromContext(r)
+				parentID = ParentSpanIDFromContext(r)
+				traceParent = r.Header.Get("traceparent")
+				traceState = r.Header.Get("tracestate")
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("traceparent", tt.traceParent)
+			req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Regexp(t, "^[0-9a-f]{32}$", traceID)
+			assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+			assert.Empty(t, parentID)
+			assert.Regexp(t, traceParentPattern, traceParent)
+			assert.Empty(t, traceState)
+		})
+	}
+}
+
+func TestTraceContext_FutureVersion(t *testing.T) {
+	var traceID string
+	handler := TraceContext()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = TraceIDFromContext(r)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("traceparent", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+}
+
+func TestTraceContext_NewTrace(t *testing.T) {
+	var traceIDs []string
+	var traceParent string
+	handler := TraceContext(WithTraceResponseHeader(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceIDs = append(traceIDs, TraceIDFromContext(r))
+		traceParent = r.Header.Get("traceparent")
+		assert.Empty(t, ParentSpanIDFromContext(r))
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		matches := traceParentPattern.FindStringSubmatch(traceParent)
+		if assert.NotNil(t, matches) {
+			assert.Equal(t, traceIDs[i], matches[1])
+			assert.Equal(t, "00", matches[3])
+		}
+		assert.Equal(t, traceParent, rr.Header().Get("traceparent"))
+	}
+
+	assert.NotEqual(t, traceIDs[0], traceIDs[1])
+}
+
+func TestTraceContext_NotUsed(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+
+	assert.Empty(t, TraceIDFromContext(req))
+	assert.Empty(t, SpanIDFromContext(req))
+	assert.Empty(t, ParentSpanIDFromContext(req))
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type transformResponseConfig struct {
+	contentTypes map[string]bool
+	maxSize      int
+}
+
+type TransformResponseOption func(*transformResponseConfig)
+
+// WithTransformContentTypes sets the content types of responses that
+// TransformResponse will transform, replacing the default of "text/html".
+// Wildcards for the main type (e.g. "text/*") are supported.
+func WithTransformContentTypes(types ...string) TransformResponseOption {
+	return func(config *transformResponseConfig) {
+		config.contentTypes = make(map[string]bool)
+		for i := range types {
+			config.contentTypes[types[i]] = true
+		}
+	}
+}
+
+// WithTransformMaxSize sets the maximum number of bytes of a response that
+// TransformResponse will buffer. Responses larger than this are sent to the
+// client untransformed. Defaults to 1MiB.
+func WithTransformMaxSize(size int) TransformResponseOption {
+	return func(config *transformResponseConfig) {
+		config.maxSize = size
+	}
+}
+
+// TransformResponse is a middleware that applies the given function to the
+// bodies of responses, e.g. to replace placeholders in HTML pages. The
+// function is called with the response's media type (without any parameters)
+// and its complete body, and returns the body to send instead. The
+// Content-Length header is updated to match.
+//
+// Only responses with a content type set by WithTransformContentTypes are
+// buffered and transformed; others are streamed through untouched. Responses
+// are also passed through if they exceed the size set by WithTransformMaxSize,
+// if the handler flushes them, if they have a Content-Encoding, if they are
+// partial (206) responses, or if they are responses to HEAD requests.
+func TransformResponse(fn func(contentType string, body []byte) []byte, opts ...TransformResponseOption) func(http.Handler) http.Handler {
+	config := &transformResponseConfig{
+		contentTypes: map[string]bool{"text/html": true},
+		maxSize:      1024 * 1024,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped := &transformResponseWrapper{
+				ResponseWriter: w,
+				conf:           config,
+				fn:             fn,
+			}
+			next.ServeHTTP(wrapped, r)
+			wrapped.finish()
+		})
+	}
+}
+
+type transformResponseWrapper struct {
+	http.ResponseWriter
+	conf        *transformResponseConfig
+	fn          func(string, []byte) []byte
+	contentType string
+	buffer      bytes.Buffer
+	status      int
+	headers     bool
+	buffering   bool
+}
+
+func (t *transformResponseWrapper) WriteHeader(code int) {
+	if isInformational(code) {
+		t.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if t.headers {
+		return
+	}
+	t.headers = true
+
+	header := t.ResponseWriter.Header()
+	contentType, _, _ := strings.Cut(header.Get("Content-Type"), ";")
+	t.contentType = strings.TrimSpace(strings.ToLower(contentType))
+
+	transform, _ := lookupContentType(t.conf.contentTypes, t.contentType)
+	if transform && t.transformable(code) {
+		t.buffering = true
+		t.status = code
+		return
+	}
+
+	t.ResponseWriter.WriteHeader(code)
+}
+
+// transformable determines whether a response with the given status code can
+// be transformed, based on its status and headers. Partial and encoded bodies
+// can't be transformed meaningfully, and some statuses have no body.
+func (t *transformResponseWrapper) transformable(code int) bool {
+	header := t.ResponseWriter.Header()
+	return code != http.StatusNoContent &&
+		code != http.StatusNotModified &&
+		code != http.StatusPartialContent &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == ""
+}
+
+func (t *transformResponseWrapper) Write(p []byte) (int, error) {
+	if !t.headers {
+		t.WriteHeader(http.StatusOK)
+	}
+
+	if t.buffering && t.buffer.Len()+len(p) > t.conf.maxSize {
+		if err := t.passThrough(); err != nil {
+			return 0, err
+		}
+	}
+
+	if t.buffering {
+		return t.buffer.Write(p)
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+// passThrough sends the headers and any buffered data untransformed, and
+// switches to writing directly to the underlying writer.
+func (t *transformResponseWrapper) passThrough() error {
+	t.buffering = false
+	t.ResponseWriter.WriteHeader(t.status)
+	if t.buffer.Len() == 0 {
+		return nil
+	}
+
+	_, err := t.ResponseWriter.Write(t.buffer.Bytes())
+	t.buffer = bytes.Buffer{}
+	return err
+}
+
+// finish transforms and sends the buffered response, if there is one.
+func (t *transformResponseWrapper) finish() {
+	if !t.buffering {
+		return
+	}
+
+	body := t.fn(t.contentType, t.buffer.Bytes())
+	t.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	t.ResponseWriter.WriteHeader(t.status)
+	if len(body) > 0 {
+		t.ResponseWriter.Write(body)
+	}
+}
+
+func (t *transformResponseWrapper) Flush() {
+	if !t.headers {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.buffering && t.passThrough() != nil {
+		return
+	}
+	if flusher, ok := t.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
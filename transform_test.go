@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func replaceDomain(_ string, body []byte) []byte {
+	return bytes.ReplaceAll(body, []byte("{{DOMAIN}}"), []byte("example.com"))
+}
+
+func TestTransformResponse_Replacement(t *testing.T) {
+	handler := TransformResponse(replaceDomain)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", "37")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`<a href="https://{{DOMAIN}}/">`))
+		w.Write([]byte(`Home</a>`))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, `<a href="https://example.com/">Home</a>`, rr.Body.String())
+	assert.Equal(t, "39", rr.Header().Get("Content-Length"))
+}
+
+func TestTransformResponse_ContentType(t *testing.T) {
+	var contentType string
+	handler := TransformResponse(func(ct string, body []byte) []byte {
+		contentType = ct
+		return body
+	}, WithTransformContentTypes("text/*"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "Text/CSS; charset=utf-8")
+		w.Write([]byte("body {}"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "text/css", contentType)
+	assert.Equal(t, "body {}", rr.Body.String())
+}
+
+func TestTransformResponse_Bypass(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		encoding    string
+	}{
+		{
+			name:        "non-matching type",
+			method:      http.MethodGet,
+			contentType: "application/json",
+		},
+		{
+			name:        "encoded",
+			method:      http.MethodGet,
+			contentType: "text/html",
+			encoding:    "gzip",
+		},
+		{
+			name:        "head request",
+			method:      http.MethodHead,
+			contentType: "text/html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := TransformResponse(func(string, []byte) []byte {
+				called = true
+				return nil
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write([]byte("{{DOMAIN}}"))
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/", nil))
+
+			assert.False(t, called)
+			assert.Equal(t, "{{DOMAIN}}", rr.Body.String())
+			assert.Empty(t, rr.Header().Get("Content-Length"))
+		})
+	}
+}
+
+func TestTransformResponse_PartialContent(t *testing.T) {
+	called := false
+	handler := TransformResponse(func(_ string, body []byte) []byte {
+		called = true
+		return body
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Range", "bytes 0-9/100")
+		w.Header().Set("Content-Length", "10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("{{DOMAIN}}"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "bytes 0-9/100", rr.Header().Get("Content-Range"))
+	assert.Equal(t, "10", rr.Header().Get("Content-Length"))
+	assert.Equal(t, "{{DOMAIN}}", rr.Body.String())
+}
+
+func TestTransformResponse_MaxSize(t *testing.T) {
+	called := false
+	handler := TransformResponse(func(_ string, body []byte) []byte {
+		called = true
+		return body
+	}, WithTransformMaxSize(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("{{DOMAIN}}"))
+		w.Write([]byte("!"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.False(t, called)
+	assert.Equal(t, "{{DOMAIN}}!", rr.Body.String())
+}
+
+func TestTransformResponse_Flush(t *testing.T) {
+	called := false
+	handler := TransformResponse(func(_ string, body []byte) []byte {
+		called = true
+		return body
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("{{DOMAIN}}"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("!"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.False(t, called)
+	assert.True(t, rr.Flushed)
+	assert.Equal(t, "{{DOMAIN}}!", rr.Body.String())
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type versionHeaderConfig struct {
+	headerName   string
+	endpoint     string
+	endpointInfo map[string]string
+}
+
+type VersionHeaderOption func(*versionHeaderConfig)
+
+// WithVersionHeaderName sets the name of the header that VersionHeader adds
+// to responses. Defaults to "X-App-Version".
+func WithVersionHeaderName(name string) VersionHeaderOption {
+	return func(config *versionHeaderConfig) {
+		config.headerName = name
+	}
+}
+
+// WithVersionEndpoint makes VersionHeader respond to GET and HEAD requests for
+// the given path with a JSON object containing the version (under the key
+// "version") and any additional build metadata in info, such as the commit
+// hash or build time. Requests for the path are not passed to the next
+// handler. By default, no endpoint is served.
+func WithVersionEndpoint(path string, info map[string]string) VersionHeaderOption {
+	return func(config *versionHeaderConfig) {
+		config.endpoint = path
+		config.endpointInfo = info
+	}
+}
+
+// VersionHeader is a middleware that adds the given version to every response
+// in a header, so that it's easy to verify which version of an application is
+// serving requests (e.g. during a deployment). The header is set as late as
+// possible, replacing any value set by the next handler.
+func VersionHeader(version string, opts ...VersionHeaderOption) func(http.Handler) http.Handler {
+	config := &versionHeaderConfig{
+		headerName: "X-App-Version",
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var body []byte
+	if config.endpoint != "" {
+		info := map[string]string{}
+		for k, v := range config.endpointInfo {
+			info[k] = v
+		}
+		info["version"] = version
+		body, _ = json.Marshal(info)
+	}
+
+	headers := Headers(WithHeaderOverride(config.headerName, version))
+
+	return func(next http.Handler) http.Handler {
+		return headers(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if body != nil && r.URL.Path == config.endpoint && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Cache-Control", "no-store")
+				w.WriteHeader(http.StatusOK)
+				if r.Method == http.MethodGet {
+					_, _ = w.Write(body)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
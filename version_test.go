@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionHeader(t *testing.T) {
+	handler := VersionHeader("1.2.3")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "1.2.3", rr.Header().Get("X-App-Version"))
+	assert.Equal(t, "hello", rr.Body.String())
+}
+
+func TestVersionHeader_NoWrites(t *testing.T) {
+	handler := VersionHeader("1.2.3")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "1.2.3", rr.Header().Get("X-App-Version"))
+}
+
+func TestVersionHeader_ReplacesHandlerValue(t *testing.T) {
+	handler := VersionHeader("1.2.3", WithVersionHeaderName("X-Build"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Build", "stale")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, []string{"1.2.3"}, rr.Header().Values("X-Build"))
+	assert.Empty(t, rr.Header().Get("X-App-Version"))
+}
+
+func TestVersionHeader_Endpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "get endpoint",
+			method:       http.MethodGet,
+			path:         "/version",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"commit":"abc123","version":"1.2.3"}`,
+		},
+		{
+			name:         "head endpoint",
+			method:       http.MethodHead,
+			path:         "/version",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "post endpoint",
+			method:       http.MethodPost,
+			path:         "/version",
+			expectedCode: http.StatusTeapot,
+			expectedBody: "next",
+		},
+		{
+			name:         "other path",
+			method:       http.MethodGet,
+			path:         "/versions",
+			expectedCode: http.StatusTeapot,
+			expectedBody: "next",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := VersionHeader("1.2.3", WithVersionEndpoint("/version", map[string]string{
+				"commit": "abc123",
+			}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("next"))
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.expectedBody, rr.Body.String())
+			assert.Equal(t, "1.2.3", rr.Header().Get("X-App-Version"))
+		})
+	}
+}
+
+func TestVersionHeader_EndpointContentType(t *testing.T) {
+	handler := VersionHeader("1.2.3", WithVersionEndpoint("/version", nil))(http.NotFoundHandler())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `{"version":"1.2.3"}`, rr.Body.String())
+}
@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WebhookDedupStore records the IDs of webhook events processed by the
+// WebhookDedup middleware. Implementations must be safe for concurrent use.
+type WebhookDedupStore interface {
+	// MarkSeen records the given event ID, and reports whether it had already
+	// been recorded. This must be atomic, so that concurrent deliveries of the
+	// same event are not both reported as new.
+	MarkSeen(id string) bool
+	// Forget removes the given event ID, so that it can be processed again.
+	Forget(id string)
+}
+
+type webhookDedupConfig struct {
+	headerName string
+}
+
+type WebhookDedupOption func(*webhookDedupConfig)
+
+// WithWebhookIDHeader sets the name of the header that the WebhookDedup
+// middleware reads event IDs from. Defaults to "X-Event-ID".
+func WithWebhookIDHeader(headerName string) WebhookDedupOption {
+	return func(config *webhookDedupConfig) {
+		config.headerName = headerName
+	}
+}
+
+// WebhookDedup is a middleware that ensures each webhook event is only
+// processed once, even if the provider delivers it multiple times.
+//
+// The first request with a given event ID is passed to the next handler.
+// Subsequent requests with the same ID are acknowledged with an empty 200
+// response without invoking the next handler. Unlike Idempotency, the
+// original response is not recorded or replayed.
+//
+// If the next handler panics or responds with a server error (a status of 500
+// or above), the event ID is forgotten so that the provider's retry will be
+// processed. A duplicate that arrives while the first delivery is still being
+// handled waits for it to complete, so that it is only acknowledged once the
+// event has been processed successfully, and is otherwise processed itself.
+// This only covers deliveries handled by the same middleware instance.
+//
+// Requests without an event ID are always passed through to the next handler.
+func WebhookDedup(store WebhookDedupStore, opts ...WebhookDedupOption) func(http.Handler) http.Handler {
+	config := &webhookDedupConfig{
+		headerName: "X-Event-ID",
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	var mutex sync.Mutex
+	inFlight := make(map[string]chan struct{})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(config.headerName)
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var done chan struct{}
+			for done == nil {
+				mutex.Lock()
+				wait, ok := inFlight[id]
+				if !ok {
+					done = make(chan struct{})
+					inFlight[id] = done
+				}
+				mutex.Unlock()
+
+				if ok {
+					select {
+					case <-wait:
+					case <-r.Context().Done():
+						return
+					}
+				}
+			}
+
+			defer func() {
+				mutex.Lock()
+				delete(inFlight, id)
+				mutex.Unlock()
+				close(done)
+			}()
+
+			if store.MarkSeen(id) {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			wrapped := &statusRecorder{
+				ResponseWriter: w,
+			}
+
+			completed := false
+			defer func() {
+				// This must happen before the ID is released, so that a waiting
+				// duplicate is processed rather than acknowledged.
+				if !completed || wrapped.status >= 500 {
+					store.Forget(id)
+				}
+			}()
+
+			next.ServeHTTP(wrapped, r)
+			completed = true
+		})
+	}
+}
+
+// MemoryWebhookDedupStore is an in-memory WebhookDedupStore that forgets
+// event IDs after a fixed TTL.
+type MemoryWebhookDedupStore struct {
+	ttl       time.Duration
+	clock     func() time.Time
+	mutex     sync.Mutex
+	entries   map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryWebhookDedupStore creates a new in-memory WebhookDedupStore that
+// remembers event IDs for the given TTL. The TTL should be longer than the
+// period over which the webhook provider retries deliveries.
+func NewMemoryWebhookDedupStore(ttl time.Duration) *MemoryWebhookDedupStore {
+	return &MemoryWebhookDedupStore{
+		ttl:     ttl,
+		clock:   time.Now,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// MarkSeen records the given event ID, and reports whether it had already
+// been recorded and not expired. Expired entries are removed from the store at
+// the same time, at most once per TTL.
+func (m *MemoryWebhookDedupStore) MarkSeen(id string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.clock()
+	m.sweep(now)
+
+	if expires, ok := m.entries[id]; ok && now.Before(expires) {
+		return true
+	}
+
+	m.entries[id] = now.Add(m.ttl)
+	return false
+}
+
+// sweep removes any expired entries, at most once per TTL.
+func (m *MemoryWebhookDedupStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.ttl {
+		return
+	}
+	m.lastSweep = now
+
+	for k := range m.entries {
+		if !now.Before(m.entries[k]) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+// Forget removes the given event ID from the store.
+func (m *MemoryWebhookDedupStore) Forget(id string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, id)
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func webhookRequest(id string) *http.Request {
+	req := httptest.NewRequest("POST", "/webhook", nil)
+	if id != "" {
+		req.Header.Set("X-Event-ID", id)
+	}
+	return req
+}
+
+func TestWebhookDedup_FirstDelivery(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("processed"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, webhookRequest("evt_1"))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "processed", rr.Body.String())
+}
+
+func TestWebhookDedup_DuplicateDelivery(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("processed"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, webhookRequest("evt_1"))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_2"))
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookDedup_NoID(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest(""))
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest(""))
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookDedup_CustomHeader(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour), WithWebhookIDHeader("X-GitHub-Delivery"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/webhook", nil)
+		req.Header.Set("X-GitHub-Delivery", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookDedup_ServerErrorRetried(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, webhookRequest("evt_1"))
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestWebhookDedup_PanicRetried(t *testing.T) {
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+	}))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookDedup_Concurrent(t *testing.T) {
+	var mutex sync.Mutex
+	calls := 0
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		calls++
+		mutex.Unlock()
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), webhookRequest("evt_1"))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookDedup_ConcurrentDuplicateAfterFailure(t *testing.T) {
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := WebhookDedup(NewMemoryWebhookDedupStore(time.Hour))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	first := httptest.NewRecorder()
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		handler.ServeHTTP(first, webhookRequest("evt_1"))
+	}()
+	<-started
+
+	duplicate := httptest.NewRecorder()
+	duplicateDone := make(chan struct{})
+	go func() {
+		defer close(duplicateDone)
+		handler.ServeHTTP(duplicate, webhookRequest("evt_1"))
+	}()
+
+	select {
+	case <-duplicateDone:
+		t.Fatal("duplicate was answered while the first delivery was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-firstDone
+	<-duplicateDone
+
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Equal(t, http.StatusAccepted, duplicate.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMemoryWebhookDedupStore_Expiry(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryWebhookDedupStore(time.Minute)
+	store.clock = func() time.Time { return now }
+
+	assert.False(t, store.MarkSeen("evt_1"))
+	assert.True(t, store.MarkSeen("evt_1"))
+
+	now = now.Add(time.Minute)
+	assert.False(t, store.MarkSeen("evt_1"))
+	assert.Len(t, store.entries, 1)
+
+	store.Forget("evt_1")
+	assert.False(t, store.MarkSeen("evt_1"))
+}
+
+func TestMemoryWebhookDedupStore_Sweep(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := NewMemoryWebhookDedupStore(time.Minute)
+	store.clock = func() time.Time { return now }
+
+	markSeen := func(offset time.Duration, id string) bool {
+		now = start.Add(offset)
+		return store.MarkSeen(id)
+	}
+
+	markSeen(0, "a")
+	markSeen(30*time.Second, "b")
+	markSeen(65*time.Second, "c")
+	assert.Len(t, store.entries, 2, "a should be swept")
+
+	// b has expired, but the last sweep was too recent to sweep again
+	assert.False(t, markSeen(100*time.Second, "d"))
+	assert.Len(t, store.entries, 3)
+	assert.False(t, markSeen(100*time.Second, "b"), "expired entries should not be reported as seen")
+
+	markSeen(130*time.Second, "e")
+	assert.Len(t, store.entries, 3, "c should be swept")
+}
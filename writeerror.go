@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+)
+
+type writeErrorLogConfig struct {
+	logger func(r *http.Request, err error)
+}
+
+type WriteErrorLogOption func(*writeErrorLogConfig)
+
+// WithWriteErrorLogger configures the function that WriteErrorLog will call
+// when writing a response fails.
+func WithWriteErrorLogger(logger func(r *http.Request, err error)) WriteErrorLogOption {
+	return func(config *writeErrorLogConfig) {
+		config.logger = logger
+	}
+}
+
+// WriteErrorLog is a middleware that logs errors that occur when writing the
+// response body, such as when the client has disconnected. Handlers typically
+// ignore these errors.
+//
+// The logger is only called for the first failed write in each request, as
+// subsequent writes will usually fail for the same reason.
+func WriteErrorLog(opts ...WriteErrorLogOption) func(http.Handler) http.Handler {
+	config := &writeErrorLogConfig{logger: defaultWriteErrorLogger}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&writeErrorWrapper{
+				ResponseWriter: w,
+				req:            r,
+				conf:           config,
+			}, r)
+		})
+	}
+}
+
+func defaultWriteErrorLogger(r *http.Request, err error) {
+	log.Printf("error writing response: %v", err)
+}
+
+type writeErrorWrapper struct {
+	http.ResponseWriter
+	req    *http.Request
+	conf   *writeErrorLogConfig
+	logged bool
+}
+
+func (e *writeErrorWrapper) Write(b []byte) (int, error) {
+	n, err := e.ResponseWriter.Write(b)
+	if err != nil && !e.logged {
+		e.logged = true
+		e.conf.logger(e.req, err)
+	}
+	return n, err
+}
+
+func (e *writeErrorWrapper) Flush() {
+	if flusher, ok := e.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (f *failingWriter) Write([]byte) (int, error) {
+	return 0, f.err
+}
+
+func TestWriteErrorLog_Error(t *testing.T) {
+	var loggedRequest *http.Request
+	var loggedErrors []error
+
+	handler := WriteErrorLog(WithWriteErrorLogger(func(r *http.Request, err error) {
+		loggedRequest = r
+		loggedErrors = append(loggedErrors, err)
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("first"))
+		assert.ErrorIs(t, err, syscall.EPIPE)
+		w.Write([]byte("second"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	writer := &failingWriter{ResponseWriter: httptest.NewRecorder(), err: syscall.EPIPE}
+
+	handler.ServeHTTP(writer, req)
+
+	assert.Equal(t, req, loggedRequest)
+	assert.Len(t, loggedErrors, 1)
+	assert.ErrorIs(t, loggedErrors[0], syscall.EPIPE)
+}
+
+func TestWriteErrorLog_NoError(t *testing.T) {
+	called := false
+
+	handler := WriteErrorLog(WithWriteErrorLogger(func(r *http.Request, err error) {
+		called = true
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("test content"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	assert.False(t, called)
+	assert.Equal(t, "test content", rr.Body.String())
+}